@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// parseCPUList parses a CPU list such as "0-3,6" into CPU numbers, each of
+// which must be below ncpu.
+func parseCPUList(list string, ncpu int) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		if first < 0 || last >= ncpu {
+			return nil, fmt.Errorf("CPU %q out of range, this machine has CPUs 0-%d", part, ncpu-1)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// applyAffinity pins a process to the CPUs in list.
+func applyAffinity(pid int32, name, list string) tea.Cmd {
+	return func() tea.Msg {
+		if err := setCPUAffinity(pid, list); err != nil {
+			return actionResultMsg{err: fmt.Errorf("failed to set CPU affinity of %s (%d): %w", name, pid, err)}
+		}
+		return actionResultMsg{}
+	}
+}
+
+// updateAffinityInput captures key presses while a new CPU affinity is
+// being typed in the detail view.
+func (m model) updateAffinityInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEnter:
+		m.editAff = false
+		if m.affInput == "" {
+			return m, nil
+		}
+		m.err = nil
+		return m, applyAffinity(m.detailProc.PID, m.detailProc.Name, m.affInput)
+	case tea.KeyEsc:
+		m.editAff = false
+	case tea.KeyBackspace:
+		if n := len(m.affInput); n > 0 {
+			m.affInput = m.affInput[:n-1]
+		}
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if (r >= '0' && r <= '9') || r == ',' || r == '-' {
+				m.affInput += string(r)
+			}
+		}
+	}
+	return m, nil
+}
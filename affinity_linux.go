@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// affinitySupported reports whether CPU affinity can be read and set.
+const affinitySupported = true
+
+// cpuAffinity returns the CPUs the process may run on as a list such as
+// "0-3,6", read from /proc since gopsutil doesn't expose it.
+func cpuAffinity(pid int32) (string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "Cpus_allowed_list:"); ok {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no CPU affinity for PID %d", pid)
+}
+
+// setCPUAffinity pins the process to the CPUs in list, such as "0-3,6".
+// Changing another user's process needs root.
+func setCPUAffinity(pid int32, list string) error {
+	cpus, err := parseCPUList(list, runtime.NumCPU())
+	if err != nil {
+		return err
+	}
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return unix.SchedSetaffinity(int(pid), &set)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// affinitySupported reports whether CPU affinity can be read and set.
+const affinitySupported = false
+
+// cpuAffinity is not supported on this platform.
+func cpuAffinity(pid int32) (string, error) {
+	return "", errors.New("CPU affinity is not supported on this platform")
+}
+
+// setCPUAffinity is not supported on this platform.
+func setCPUAffinity(pid int32, list string) error {
+	return errors.New("CPU affinity is not supported on this platform")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultAlertTicks is how many refreshes in a row a limit must be
+	// exceeded before an alert is raised, so a brief spike doesn't trigger
+	// one.
+	defaultAlertTicks = 3
+
+	// defaultAlertCooldown is the least time between two runs of the
+	// --on-alert command while an alert stays raised.
+	defaultAlertCooldown = 5 * time.Minute
+
+	// alertCommandTimeout bounds how long the --on-alert command may run
+	// before it is killed.
+	alertCommandTimeout = 30 * time.Second
+
+	// alertCommandWaitDelay bounds how long xtop waits for the command's
+	// output once it has exited or been killed, in case something it
+	// started in the background still holds the output open.
+	alertCommandWaitDelay = 5 * time.Second
+)
+
+// alerts watches total CPU and memory usage against the --alert-cpu and
+// --alert-mem limits. A limit of 0 is off.
+type alerts struct {
+	cpuLimit float64
+	memLimit float64
+	ticks    int
+	beep     bool
+	command  string // run through the shell while an alert is raised
+	cooldown time.Duration
+
+	cpuBreaches int
+	memBreaches int
+	flash       bool      // alternates each refresh while an alert is raised
+	lastRun     time.Time // when command was last started
+}
+
+// alertCommandMsg reports the outcome of running the --on-alert command.
+type alertCommandMsg struct {
+	err error
+}
+
+// update records one sample and reports whether an alert was raised that
+// wasn't raised before.
+func (a *alerts) update(stats systemStats) bool {
+	wasActive := a.active()
+
+	if a.cpuLimit > 0 && len(stats.cpuPercent) > 0 && stats.cpuTotal >= a.cpuLimit {
+		a.cpuBreaches++
+	} else {
+		a.cpuBreaches = 0
+	}
+	if a.memLimit > 0 && stats.memStats != nil && stats.memStats.UsedPercent >= a.memLimit {
+		a.memBreaches++
+	} else {
+		a.memBreaches = 0
+	}
+
+	a.flash = a.active() && !a.flash
+	return a.active() && !wasActive
+}
+
+func (a alerts) cpuAlert() bool {
+	return a.cpuLimit > 0 && a.cpuBreaches >= a.ticks
+}
+
+func (a alerts) memAlert() bool {
+	return a.memLimit > 0 && a.memBreaches >= a.ticks
+}
+
+func (a alerts) active() bool {
+	return a.cpuAlert() || a.memAlert()
+}
+
+// message describes the raised alerts, such as "CPU 95% ≥ 90%".
+func (a alerts) message(stats systemStats) string {
+	var msg string
+	if a.cpuAlert() {
+		msg = fmt.Sprintf("CPU %.0f%% ≥ %.0f%%", stats.cpuTotal, a.cpuLimit)
+	}
+	if a.memAlert() && stats.memStats != nil {
+		if msg != "" {
+			msg += ", "
+		}
+		msg += fmt.Sprintf("memory %.0f%% ≥ %.0f%%", stats.memStats.UsedPercent, a.memLimit)
+	}
+	return msg
+}
+
+// hook starts the --on-alert command when an alert is raised, and again
+// every cooldown for as long as it stays raised. It returns nil when there
+// is nothing to run.
+func (a *alerts) hook(stats systemStats, now time.Time) tea.Cmd {
+	if a.command == "" || !a.active() || now.Sub(a.lastRun) < a.cooldown {
+		return nil
+	}
+	a.lastRun = now
+
+	var kinds []string
+	if a.cpuAlert() {
+		kinds = append(kinds, "cpu")
+	}
+	if a.memAlert() {
+		kinds = append(kinds, "memory")
+	}
+	env := []string{
+		"XTOP_ALERT=" + a.message(stats),
+		"XTOP_ALERT_KIND=" + strings.Join(kinds, ","),
+	}
+	if len(stats.cpuPercent) > 0 {
+		env = append(env, fmt.Sprintf("XTOP_ALERT_CPU=%.1f", stats.cpuTotal))
+	}
+	if stats.memStats != nil {
+		env = append(env, fmt.Sprintf("XTOP_ALERT_MEM=%.1f", stats.memStats.UsedPercent))
+	}
+	return runAlertCommand(a.command, env)
+}
+
+// runAlertCommand runs command through the shell with env added to xtop's
+// environment. It runs in the background, so a slow command doesn't hold
+// up the display. Its failure is only reported in the status line, with
+// the output collapsed onto that one line; it isn't written to the
+// --log file, which holds metrics only.
+func runAlertCommand(command string, env []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), alertCommandTimeout)
+		defer cancel()
+
+		shell := []string{"sh", "-c", command}
+		if runtime.GOOS == "windows" {
+			shell = []string{"cmd", "/C", command}
+		}
+		cmd := exec.CommandContext(ctx, shell[0], shell[1:]...)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.WaitDelay = alertCommandWaitDelay
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if msg := strings.Join(strings.Fields(string(out)), " "); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			return alertCommandMsg{err: err}
+		}
+		return alertCommandMsg{}
+	}
+}
+
+// bell rings the terminal bell. It writes to stderr, which is the terminal
+// the TUI is drawn on, without disturbing the rendered screen.
+func bell() tea.Msg {
+	fmt.Fprint(os.Stderr, "\a")
+	return nil
+}
@@ -0,0 +1,11 @@
+package main
+
+// lowBatteryPercent is the charge below which the battery is shown in the
+// error color.
+const lowBatteryPercent = 15
+
+// batteryStat is the charge level of the system battery.
+type batteryStat struct {
+	percent float64
+	status  string // e.g. "charging", "discharging" or "full"
+}
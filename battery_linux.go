@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// getBattery reads the first battery under /sys/class/power_supply. It
+// returns nil when there is no battery or it can't be read.
+func getBattery() *batteryStat {
+	dirs, _ := filepath.Glob("/sys/class/power_supply/BAT*")
+	for _, dir := range dirs {
+		capacity, err := os.ReadFile(filepath.Join(dir, "capacity"))
+		if err != nil {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(string(capacity)), 64)
+		if err != nil {
+			continue
+		}
+
+		batt := &batteryStat{percent: percent, status: "unknown"}
+		if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+			batt.status = strings.ToLower(strings.TrimSpace(string(status)))
+		}
+		return batt
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// getBattery is only implemented on Linux; elsewhere no battery is shown.
+func getBattery() *batteryStat {
+	return nil
+}
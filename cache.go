@@ -0,0 +1,105 @@
+package main
+
+import "sync"
+
+// processMeta is the part of ProcessInfo that stays the same for the life
+// of a process, so it only needs to be read once, or again after the
+// process execs another program.
+type processMeta struct {
+	createTime int64
+	name       string
+	cmdline    string
+	user       string
+	tgid       int32
+	sid        int32
+	tty        string
+	cgroup     string
+}
+
+// metaCache remembers processMeta between refreshes. Entries are keyed by
+// PID and only reused while the creation time still matches, so a recycled
+// PID is looked up afresh. It is safe for concurrent use, since a slow
+// refresh may still be running when the next one starts. For the same
+// reason it also tracks which PIDs are being read, so a read stuck in one
+// refresh isn't joined by another in each of the next. A nil cache never
+// hits and tracks nothing.
+type metaCache struct {
+	mu      sync.Mutex
+	entries map[int32]processMeta
+	reading map[int32]bool
+}
+
+func newMetaCache() *metaCache {
+	return &metaCache{entries: make(map[int32]processMeta), reading: make(map[int32]bool)}
+}
+
+// get returns the cached metadata for pid if it belongs to the process
+// created at createTime.
+func (c *metaCache) get(pid int32, createTime int64) (processMeta, bool) {
+	if c == nil {
+		return processMeta{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, ok := c.entries[pid]
+	if !ok || meta.createTime != createTime {
+		return processMeta{}, false
+	}
+	return meta, true
+}
+
+// put stores the metadata for pid, replacing any earlier process with the
+// same PID.
+func (c *metaCache) put(pid int32, meta processMeta) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[pid] = meta
+}
+
+// retain evicts every PID not in live so entries for exited processes
+// don't accumulate.
+func (c *metaCache) retain(live map[int32]bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for pid := range c.entries {
+		if !live[pid] {
+			delete(c.entries, pid)
+		}
+	}
+}
+
+// startRead marks pid as being read. It reports false, leaving the mark
+// alone, when an earlier read of pid hasn't finished.
+func (c *metaCache) startRead(pid int32) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reading[pid] {
+		return false
+	}
+	c.reading[pid] = true
+	return true
+}
+
+// endRead clears the mark left by startRead.
+func (c *metaCache) endRead(pid int32) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.reading, pid)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// capabilities records which per-process figures can be collected on the
+// running system. gopsutil leaves several unimplemented outside Linux,
+// such as open file descriptors on macOS and the BSDs and I/O counters on
+// macOS, and their columns would otherwise show as unknown for every
+// process.
+type capabilities struct {
+	fds     bool
+	diskIO  bool
+	cgroups bool
+	swap    bool
+}
+
+// platformCapabilities finds out what can be collected by reading xtop's
+// own process, which is always permitted, so a failure means the figure
+// isn't available at all rather than that it is restricted.
+func platformCapabilities() capabilities {
+	caps := capabilities{cgroups: runtime.GOOS == "linux", swap: runtime.GOOS == "linux"}
+
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return caps
+	}
+	_, err = self.NumFDs()
+	caps.fds = err == nil
+	_, err = self.IOCounters()
+	caps.diskIO = err == nil
+
+	return caps
+}
+
+// supports reports whether the column with the given key can be filled in.
+func (c capabilities) supports(key string) bool {
+	switch key {
+	case "fds":
+		return c.fds
+	case "diskread", "diskwrite":
+		return c.diskIO
+	case "cgroup":
+		return c.cgroups
+	case "swap":
+		return c.swap
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// containerIDPattern matches the 64 hex digit ID that Docker, containerd,
+// CRI-O and Podman put in a container's cgroup path, either as a path
+// segment of its own or inside a systemd scope such as
+// "docker-<id>.scope".
+var containerIDPattern = regexp.MustCompile(`(?:^|[-/])([0-9a-f]{64})(?:\.scope)?$`)
+
+// cgroupName sums up a cgroup path for the CGROUP column: the short
+// container ID for a containerized process, otherwise the innermost
+// systemd slice or unit. Processes in the root cgroup get "".
+func cgroupName(cgroupPath string) string {
+	cgroupPath = strings.TrimSuffix(cgroupPath, "/")
+	if cgroupPath == "" {
+		return ""
+	}
+
+	for _, segment := range strings.Split(cgroupPath, "/") {
+		if m := containerIDPattern.FindStringSubmatch(segment); m != nil {
+			return m[1][:12]
+		}
+	}
+	return path.Base(cgroupPath)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processCgroup reads the cgroup of a process from /proc and sums it up
+// with cgroupName. The unified (v2) hierarchy is preferred, falling back
+// to the systemd or memory controller on v1 hosts.
+func processCgroup(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+
+	paths := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Each line is "hierarchy-ID:controllers:path"
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) == 3 {
+			paths[fields[1]] = fields[2]
+		}
+	}
+	for _, controller := range []string{"", "name=systemd", "memory"} {
+		if name := cgroupName(paths[controller]); name != "" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// processCgroup always returns ""; cgroups only exist on Linux.
+func processCgroup(pid int32) string {
+	return ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clipboardMsg reports the outcome of copying to the clipboard. what
+// describes what was copied, such as "PID 1234".
+type clipboardMsg struct {
+	what string
+	err  error
+}
+
+// clipboardCommand returns the command that writes its input to the system
+// clipboard. On Linux and the BSDs that needs a graphical session, which
+// isn't there over SSH without X forwarding.
+func clipboardCommand() ([]string, error) {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip.exe"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		if os.Getenv("DISPLAY") != "" {
+			candidates = append(candidates,
+				[]string{"xclip", "-selection", "clipboard"},
+				[]string{"xsel", "--clipboard", "--input"})
+		}
+		if candidates == nil {
+			return nil, errors.New("no clipboard available without a graphical session")
+		}
+	}
+
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args, nil
+		}
+	}
+	var names []string
+	for _, args := range candidates {
+		names = append(names, args[0])
+	}
+	return nil, fmt.Errorf("no clipboard available (install %s)", strings.Join(names, " or "))
+}
+
+// copyToClipboard returns a command that copies text to the system
+// clipboard and reports the outcome as a clipboardMsg.
+func copyToClipboard(what, text string) tea.Cmd {
+	return func() tea.Msg {
+		args, err := clipboardCommand()
+		if err != nil {
+			return clipboardMsg{what: what, err: err}
+		}
+
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				err = fmt.Errorf("%s: %s", args[0], msg)
+			}
+			return clipboardMsg{what: what, err: err}
+		}
+		return clipboardMsg{what: what}
+	}
+}
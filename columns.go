@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// columnSpec describes a process table column: the name used to pick it
+// with --columns, the width it gets when there is room, the narrowest it
+// may be squeezed to on a small terminal, and how to render a cell.
+type columnSpec struct {
+	key      string
+	title    string
+	width    int
+	minWidth int
+	cell     func(m model, proc ProcessInfo, now time.Time) string
+}
+
+// commandKey names the column that takes whatever width is left over and
+// carries the tree prefix.
+const commandKey = "command"
+
+// allColumns lists every column in its default order.
+var allColumns = []columnSpec{
+	{"pid", "PID", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return strconv.Itoa(int(proc.PID))
+	}},
+	{"user", "USER", 10, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		return proc.User
+	}},
+	{"tty", "TTY", 7, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return proc.TTY
+	}},
+	{"nice", "NI", 4, 3, func(m model, proc ProcessInfo, now time.Time) string {
+		return strconv.Itoa(int(proc.Nice))
+	}},
+	{"cpu", "CPU%", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return fmt.Sprintf("%.*f", m.precision, proc.CPUPerc)
+	}},
+	{"cpuavg", "AVG%", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return fmt.Sprintf("%.*f", m.precision, proc.CPUAvg)
+	}},
+	{"time", "TIME+", 10, 7, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatCPUTime(proc.cpuTime)
+	}},
+	{"mem", "MEM%", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return fmt.Sprintf("%.1f", proc.MemPerc)
+	}},
+	{"res", "RES", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatBytes(proc.MemRSS)
+	}},
+	{"swap", "SWAP", 8, 5, func(m model, proc ProcessInfo, now time.Time) string {
+		if proc.Swap < 0 {
+			return "-"
+		}
+		return formatBytes(uint64(proc.Swap))
+	}},
+	{"threads", "THR", 5, 3, func(m model, proc ProcessInfo, now time.Time) string {
+		return strconv.Itoa(int(proc.Threads))
+	}},
+	{"fds", "FD", 5, 3, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatCount(proc.NumFDs)
+	}},
+	{"conns", "CONN", 5, 4, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatCount(proc.Conns)
+	}},
+	{"diskread", "DISK R", 8, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatIORate(proc.DiskReadBps)
+	}},
+	{"diskwrite", "DISK W", 8, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatIORate(proc.DiskWriteBps)
+	}},
+	{"uptime", "UPTIME", 8, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		return formatProcessUptime(proc, now)
+	}},
+	{"cgroup", "CGROUP", 14, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		if proc.Cgroup == "" {
+			return "-"
+		}
+		return proc.Cgroup
+	}},
+	{"status", "STATUS", 10, 6, func(m model, proc ProcessInfo, now time.Time) string {
+		return proc.Status
+	}},
+	{commandKey, "COMMAND", 30, 10, func(m model, proc ProcessInfo, now time.Time) string {
+		if proc.members > 1 && m.byName {
+			return fmt.Sprintf("%s ×%d", proc.Name, proc.members)
+		}
+		if proc.members > 1 {
+			return fmt.Sprintf("%s (%d procs)", m.command(proc), proc.members)
+		}
+		return m.command(proc)
+	}},
+}
+
+// compactColumns are the columns shown in the compact view.
+var compactColumns = []string{"pid", "cpu", commandKey}
+
+// columnKeys returns the names accepted by --columns.
+func columnKeys() []string {
+	keys := make([]string, len(allColumns))
+	for i, spec := range allColumns {
+		keys[i] = spec.key
+	}
+	return keys
+}
+
+// defaultColumns returns every column except CONN, CGROUP and SWAP, which
+// are only shown with --connections, --cgroups and --swap, and AVG% and
+// TIME+, which are toggled from the keyboard.
+func defaultColumns(showConns, showCgroups, showSwap bool) []string {
+	var keys []string
+	for _, key := range columnKeys() {
+		switch {
+		case key == "conns" && !showConns:
+		case key == "cgroup" && !showCgroups:
+		case key == "swap" && !showSwap:
+		case key == "cpuavg", key == "time":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// parseColumns splits a comma-separated column list such as
+// "pid,user,cpu,command", rejecting unknown and repeated names.
+func parseColumns(list string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(list, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, ok := lookupColumn(key); !ok {
+			return nil, fmt.Errorf("unknown column %q (valid columns: %s)", key, strings.Join(columnKeys(), ", "))
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("column %q is listed twice", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func lookupColumn(key string) (columnSpec, bool) {
+	for _, spec := range allColumns {
+		if spec.key == key {
+			return spec, true
+		}
+	}
+	return columnSpec{}, false
+}
+
+// columnSpecs resolves column names, which must already be valid.
+func columnSpecs(keys []string) []columnSpec {
+	specs := make([]columnSpec, 0, len(keys))
+	for _, key := range keys {
+		if spec, ok := lookupColumn(key); ok {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// tableColumns sizes specs to a terminal width columns wide. The other
+// columns keep their usual width and COMMAND, if shown, gets the rest; when
+// that doesn't leave enough room, the others give up their spare width in
+// proportion to how much each has. A width of 0, before the terminal size
+// is known, gives every column its usual width.
+func tableColumns(width int, specs []columnSpec) []table.Column {
+	widths := make([]int, len(specs))
+	for i, spec := range specs {
+		widths[i] = spec.width
+	}
+
+	if width > 0 {
+		// The table is drawn inside a border, with a column of padding on
+		// either side of every cell
+		avail := width - 4 - 2*len(specs)
+
+		flex, flexMin := -1, 0
+		used, slack := 0, 0
+		for i, spec := range specs {
+			if spec.key == commandKey {
+				flex, flexMin = i, spec.minWidth
+				continue
+			}
+			used += spec.width
+			slack += spec.width - spec.minWidth
+		}
+
+		if deficit := flexMin - (avail - used); deficit > 0 && slack > 0 {
+			deficit = min(deficit, slack)
+			for i, spec := range specs {
+				if i == flex {
+					continue
+				}
+				// Round up so the whole deficit is covered
+				shrink := ((spec.width-spec.minWidth)*deficit + slack - 1) / slack
+				widths[i] = max(spec.width-shrink, spec.minWidth)
+				used -= spec.width - widths[i]
+			}
+		}
+		if flex >= 0 {
+			widths[flex] = max(avail-used, flexMin)
+		}
+	}
+
+	columns := make([]table.Column, len(specs))
+	for i, spec := range specs {
+		columns[i] = table.Column{Title: spec.title, Width: widths[i]}
+	}
+	return columns
+}
+
+// scrollColumns returns the columns of specs that fit a terminal width
+// columns wide, squeezed as far as tableColumns allows. When they don't all
+// fit, the first column and COMMAND stay put and the others scroll, with
+// the one at offset shown first. The offset is returned clamped to the
+// columns that can scroll.
+func scrollColumns(specs []columnSpec, width, offset int) ([]columnSpec, int) {
+	fits := func(cols []columnSpec) bool {
+		need := 4
+		for _, spec := range cols {
+			need += spec.minWidth + 2
+		}
+		return need <= width
+	}
+	if width <= 0 || len(specs) < 2 || fits(specs) {
+		return specs, 0
+	}
+
+	head := specs[:1]
+	var middle, tail []columnSpec
+	for _, spec := range specs[1:] {
+		if spec.key == commandKey {
+			tail = append(tail, spec)
+		} else {
+			middle = append(middle, spec)
+		}
+	}
+	if len(middle) == 0 {
+		return specs, 0
+	}
+	// Stop scrolling once the last column is in view
+	offset = min(max(offset, 0), len(middle)-1)
+	for offset > 0 && fits(slices.Concat(head, middle[offset-1:], tail)) {
+		offset--
+	}
+
+	shown := slices.Clone(head)
+	for _, spec := range middle[offset:] {
+		if !fits(slices.Concat(shown, []columnSpec{spec}, tail)) {
+			break
+		}
+		shown = append(shown, spec)
+	}
+	return append(shown, tail...), offset
+}
+
+// row renders the table cells for a tree entry. The command is prefixed
+// with the entry's tree branch and shortened to fit its column.
+func (m model) row(entry treeEntry, now time.Time) table.Row {
+	columns := m.table.Columns()
+	row := make(table.Row, len(m.cols))
+	for i, spec := range m.cols {
+		cell := spec.cell(m, entry.proc, now)
+		if spec.key == commandKey {
+			// Count runes so tree prefixes aren't cut mid-character
+			prefix := []rune(entry.prefix)
+			width := columns[i].Width
+			if len(prefix) >= width {
+				prefix = prefix[:max(width-1, 0)]
+			}
+			cell = string(prefix) + truncateCommand(cell, width-len(prefix))
+		}
+		row[i] = cell
+	}
+	return row
+}
+
+// truncateCommand shortens a command line to at most width characters.
+// The executable's directory goes first, leaving "…/name", and then the
+// middle of what remains, so the executable name and the trailing
+// arguments, which usually tell similar commands apart, both stay visible.
+func truncateCommand(cmd string, width int) string {
+	runes := []rune(cmd)
+	if len(runes) <= width {
+		return cmd
+	}
+	if width <= 1 {
+		return string(runes[:max(width, 0)])
+	}
+
+	// Only paths are shortened; names such as "kworker/0:1" also contain
+	// slashes
+	exe, args, _ := strings.Cut(cmd, " ")
+	i := strings.LastIndexByte(exe, '/')
+	if (strings.HasPrefix(exe, "/") || strings.HasPrefix(exe, ".")) && i > 0 && i < len(exe)-1 {
+		exe = "…/" + exe[i+1:]
+		short := exe
+		if args != "" {
+			short += " " + args
+		}
+		runes = []rune(short)
+		if len(runes) <= width {
+			return short
+		}
+	}
+
+	// Keep at least the executable name at the front; a name too long for
+	// the column is cut at the end instead
+	avail := width - 1
+	head := min(max(len([]rune(exe)), avail/2), avail)
+	tail := avail - head
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+// toggleColumn shows the column named key if it is hidden, next to where it
+// sits among allColumns, or hides it if it is shown.
+func (m *model) toggleColumn(key string) {
+	if i := slices.IndexFunc(m.fullCols, func(spec columnSpec) bool { return spec.key == key }); i >= 0 {
+		m.fullCols = slices.Delete(slices.Clone(m.fullCols), i, i+1)
+	} else if spec, ok := lookupColumn(key); ok {
+		// Place it before the first shown column that follows it in the
+		// default order
+		rank := slices.Index(columnKeys(), key)
+		at := slices.IndexFunc(m.fullCols, func(s columnSpec) bool {
+			return slices.Index(columnKeys(), s.key) > rank
+		})
+		if at < 0 {
+			at = len(m.fullCols)
+		}
+		m.fullCols = slices.Insert(slices.Clone(m.fullCols), at, spec)
+	}
+	m.setCompact(m.compact)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// config is the on-disk form of the preferences that survive restarts.
+type config struct {
+	SortBy    string `json:"sortBy"`
+	Ascending bool   `json:"ascending"`
+	ThenBy    string `json:"thenBy,omitempty"`
+	Interval  string `json:"interval"`
+	MaxRows   *int   `json:"maxRows,omitempty"` // nil when left out, as 0 means no limit
+
+	// Theme names a preset or an entry in Themes
+	Theme  string           `json:"theme,omitempty"`
+	Themes map[string]Theme `json:"themes,omitempty"`
+	// Colors overrides single colors of whichever theme is in use, such
+	// as the selected row's background
+	Colors Theme `json:"colors,omitzero"`
+
+	Columns      []string `json:"columns,omitempty"`
+	Watch        []string `json:"watch,omitempty"`
+	HiddenPanels []string `json:"hiddenPanels,omitempty"`
+	ConfirmQuit  bool     `json:"confirmQuit,omitempty"`
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "xtop", "config.json"), nil
+}
+
+// loadConfig overlays the saved preferences onto opts. A missing or
+// malformed file leaves opts untouched, as does any individual value that
+// fails validation.
+func loadConfig(opts options) options {
+	cfg, err := readConfig()
+	if err != nil {
+		return opts
+	}
+	return cfg.apply(opts)
+}
+
+// readConfig reads and decodes the config file.
+func readConfig() (config, error) {
+	var cfg config
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// configModTime returns when the config file was last modified, or the
+// zero time if it doesn't exist.
+func configModTime() time.Time {
+	path, err := configPath()
+	if err != nil {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// apply overlays the values of cfg that pass validation onto opts.
+func (cfg config) apply(opts options) options {
+	if isSortKey(cfg.SortBy) {
+		opts.sortBy = cfg.SortBy
+		opts.ascending = cfg.Ascending
+	}
+	if isSortKey(cfg.ThenBy) {
+		opts.thenBy = cfg.ThenBy
+	}
+	if d, err := time.ParseDuration(cfg.Interval); err == nil && d >= minInterval {
+		opts.interval = d
+	}
+	if cfg.MaxRows != nil && *cfg.MaxRows >= 0 {
+		opts.maxRows = *cfg.MaxRows
+	}
+	// No columns means the default set, also when a reload finds the list
+	// removed
+	if len(cfg.Columns) == 0 {
+		opts.columns = nil
+	} else if keys, err := parseColumns(strings.Join(cfg.Columns, ",")); err == nil {
+		opts.columns = keys
+	}
+	opts.watch = parseWatchList(strings.Join(cfg.Watch, ","))
+	if panels, err := parsePanels(strings.Join(cfg.HiddenPanels, ",")); err == nil {
+		opts.hidePanels = panels
+	}
+	opts.themes = cfg.Themes
+	opts.colors = cfg.Colors
+	opts.confirmQuit = cfg.ConfirmQuit
+	if _, ok := lookupTheme(cfg.Theme, cfg.Themes); ok {
+		opts.theme = cfg.Theme
+	}
+
+	return opts
+}
+
+// validate reports the first value in cfg that apply would skip.
+func (cfg config) validate() error {
+	if cfg.SortBy != "" && !isSortKey(cfg.SortBy) {
+		return fmt.Errorf("unknown sortBy %q", cfg.SortBy)
+	}
+	if cfg.ThenBy != "" && !isSortKey(cfg.ThenBy) {
+		return fmt.Errorf("unknown thenBy %q", cfg.ThenBy)
+	}
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return fmt.Errorf("interval: %w", err)
+		}
+		if d < minInterval {
+			return fmt.Errorf("interval %s is shorter than %s", d, minInterval)
+		}
+	}
+	if cfg.MaxRows != nil && *cfg.MaxRows < 0 {
+		return fmt.Errorf("maxRows must not be negative")
+	}
+	if len(cfg.Columns) > 0 {
+		if _, err := parseColumns(strings.Join(cfg.Columns, ",")); err != nil {
+			return err
+		}
+	}
+	if _, err := parsePanels(strings.Join(cfg.HiddenPanels, ",")); err != nil {
+		return err
+	}
+	if cfg.Theme != "" {
+		if _, ok := lookupTheme(cfg.Theme, cfg.Themes); !ok {
+			return fmt.Errorf("unknown theme %q", cfg.Theme)
+		}
+	}
+	return nil
+}
+
+// errConfigNotReloaded marks the error shown when a changed config file
+// couldn't be applied, so a later successful reload knows to clear it.
+var errConfigNotReloaded = errors.New("config not reloaded")
+
+// reloadConfig applies the config file after it was changed while xtop is
+// running, taking its values over the current ones, including any given
+// on the command line. A file that can't be read or holds an invalid value
+// is left unapplied, with a warning in the status line.
+func (m *model) reloadConfig() {
+	cfg, err := readConfig()
+	if err == nil {
+		err = cfg.validate()
+	}
+	if err != nil {
+		m.err = fmt.Errorf("%w: %w", errConfigNotReloaded, err)
+		return
+	}
+
+	opts := cfg.apply(m.prefs())
+	m.sortBy, m.thenBy, m.ascending = opts.sortBy, opts.thenBy, opts.ascending
+	m.interval = opts.interval
+	m.maxRows = opts.maxRows
+	m.watch = opts.watch
+	m.hidePanels = opts.hidePanels
+	m.askQuit = opts.confirmQuit
+
+	m.theme, m.themes, m.colors = opts.theme, opts.themes, opts.colors
+	opts.noColor = m.noColor
+	theme := resolveTheme(opts)
+	m.styles = newStyles(theme)
+	m.table.SetStyles(tableStyles(theme))
+	m.ports.SetStyles(tableStyles(theme))
+
+	if !slices.Equal(opts.columns, m.columnList) {
+		m.columnList = opts.columns
+		m.fullCols = shownColumns(m.columnList, m.showConns, m.showCgroup, m.showSwap)
+	}
+	// Everything now comes from the file, so it can all be saved back
+	m.oneOff = nil
+	// Any other error, such as from a failing collector, is left showing
+	if errors.Is(m.err, errConfigNotReloaded) {
+		m.err = nil
+	}
+	m.notice = "Config reloaded"
+	m.layoutColumns()
+}
+
+// saveConfig writes the preferences in opts, creating the config
+// directory if needed.
+func saveConfig(opts options) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	cfg := config{
+		SortBy:       opts.sortBy,
+		Ascending:    opts.ascending,
+		ThenBy:       opts.thenBy,
+		Interval:     opts.interval.String(),
+		MaxRows:      &opts.maxRows,
+		Theme:        opts.theme,
+		Themes:       opts.themes,
+		Colors:       opts.colors,
+		Columns:      opts.columns,
+		Watch:        opts.watch,
+		HiddenPanels: opts.hidePanels,
+		ConfirmQuit:  opts.confirmQuit,
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
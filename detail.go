@@ -0,0 +1,246 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// viewMode selects what is shown below the header.
+type viewMode int
+
+const (
+	viewTable viewMode = iota
+	viewDetail
+	viewDiff
+	viewPorts
+)
+
+// processDetail holds the fields shown in the detail view that are too
+// costly to collect for every process on every refresh. Counts are -1 and
+// strings empty when the value can't be read.
+type processDetail struct {
+	pid       int32
+	exe       string
+	cwd       string
+	openFiles int
+	env       []string // sorted NAME=value entries
+	envErr    error
+	affinity  string
+	goBuild   string // Go version and module, "no" for other executables
+}
+
+// detailMsg carries a freshly gathered processDetail.
+type detailMsg processDetail
+
+// fetchDetail gathers the detail view fields for a single process, reusing
+// what can't have changed since prev, which may be nil.
+func fetchDetail(pid int32, prev *processDetail) tea.Cmd {
+	return func() tea.Msg {
+		return detailMsg(readDetail(pid, prev))
+	}
+}
+
+// readDetail reads the detail view fields of a single process. The Go build
+// info means parsing the executable, so it is taken from prev, when given,
+// unless the process has since run another executable.
+func readDetail(pid int32, prev *processDetail) processDetail {
+	d := processDetail{pid: pid, openFiles: -1}
+
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return d
+	}
+	d.exe, _ = p.Exe()
+	if prev != nil && prev.pid == pid && prev.exe == d.exe {
+		d.goBuild = prev.goBuild
+	} else {
+		d.goBuild = goBuild(d.exe)
+	}
+	d.cwd, _ = p.Cwd()
+	if files, err := p.OpenFiles(); err == nil {
+		d.openFiles = len(files)
+	}
+	d.env, d.envErr = p.Environ()
+	sort.Strings(d.env)
+	d.affinity, _ = cpuAffinity(pid)
+
+	return d
+}
+
+// goBuild describes the Go version and main module an executable was
+// built with, as recorded by the Go toolchain. It returns "no" for an
+// executable that wasn't built with Go and "" when the file can't be read.
+func goBuild(exe string) string {
+	if exe == "" {
+		return ""
+	}
+	info, err := buildinfo.ReadFile(exe)
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return ""
+	case err != nil:
+		return "no"
+	case info.Main.Path != "":
+		return fmt.Sprintf("%s (%s)", info.GoVersion, info.Main.Path)
+	}
+	return info.GoVersion
+}
+
+// openDetail switches to the detail view for the selected process.
+func (m model) openDetail() (tea.Model, tea.Cmd) {
+	proc, ok := m.selectedTarget()
+	if !ok {
+		return m, nil
+	}
+	m.mode = viewDetail
+	m.detailProc = proc
+	m.detail = nil
+	m.envScroll = 0
+	m.envReveal = false
+	m.editAff = false
+	return m, fetchDetail(proc.PID, nil)
+}
+
+// updateDetail captures key presses while the detail view is open.
+func (m model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editAff {
+		return m.updateAffinityInput(msg)
+	}
+	switch msg.String() {
+	case "esc", "enter":
+		m.mode = viewTable
+		m.detail = nil
+	case "q":
+		return m.requestQuit()
+	case "ctrl+c":
+		return m.quit()
+	case "up", "k":
+		m.scrollEnv(-1)
+	case "down", "j":
+		m.scrollEnv(1)
+	case "pgup":
+		m.scrollEnv(-envPanelHeight)
+	case "pgdown":
+		m.scrollEnv(envPanelHeight)
+	case "r":
+		m.envReveal = !m.envReveal
+	case "e":
+		return m, dumpProcess(m.detailProc, !m.noRedact)
+	case "a":
+		if affinitySupported {
+			m.editAff = true
+			m.affInput = ""
+			if m.detail != nil {
+				m.affInput = m.detail.affinity
+			}
+		}
+	}
+	return m, nil
+}
+
+// refreshDetail picks up the detail process's latest figures from the
+// table rows, so they match how the table shows them, and re-reads its
+// extra fields. A process that has exited or left the table keeps its last
+// known figures.
+func (m *model) refreshDetail() tea.Cmd {
+	for _, proc := range m.rows {
+		if proc.PID == m.detailProc.PID {
+			m.detailProc = proc
+			return fetchDetail(proc.PID, m.detail)
+		}
+	}
+	return nil
+}
+
+// renderDetail renders everything known about the detail process.
+func (m model) renderDetail() string {
+	proc := m.detailProc
+	d := m.detail
+
+	unknown := func(s string) string {
+		if s == "" {
+			return "?"
+		}
+		return s
+	}
+	count := func(n int) string {
+		if n < 0 {
+			return "?"
+		}
+		return strconv.Itoa(n)
+	}
+
+	started := "?"
+	if proc.StartTime > 0 {
+		started = time.UnixMilli(proc.StartTime).Format("2006-01-02 15:04:05")
+	}
+
+	fields := [][2]string{
+		{"PID", strconv.Itoa(int(proc.PID))},
+		{"Parent PID", strconv.Itoa(int(proc.PPID))},
+		{"Name", proc.Name},
+		{"User", unknown(proc.User)},
+		{"Status", unknown(proc.Status)},
+		{"Nice", strconv.Itoa(int(proc.Nice))},
+		{"CPU", fmt.Sprintf("%.1f%%", proc.CPUPerc)},
+		{"Memory", fmt.Sprintf("%.1f%% (%s)", proc.MemPerc, formatBytes(proc.MemRSS))},
+		{"Threads", strconv.Itoa(int(proc.Threads))},
+		{"Started", started},
+	}
+	if d == nil {
+		fields = append(fields, [2]string{"", "Loading…"})
+	} else {
+		envVars := len(d.env)
+		if d.envErr != nil {
+			envVars = -1
+		}
+		affinity := unknown(d.affinity)
+		switch {
+		case !affinitySupported:
+			affinity = "-"
+		case m.editAff:
+			affinity = m.styles.search.Render(m.affInput+"█") + "  [enter] Apply • [esc] Cancel"
+		}
+		fields = append(fields,
+			[2]string{"Executable", unknown(d.exe)},
+			[2]string{"Go binary", unknown(d.goBuild)},
+			[2]string{"Working dir", unknown(d.cwd)},
+			[2]string{"Open files", count(d.openFiles)},
+			[2]string{"Env vars", count(envVars)},
+			[2]string{"CPU affinity", affinity},
+		)
+	}
+	fields = append(fields, [2]string{"Command line", unknown(proc.Cmdline)})
+
+	var b strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%-13s", field[0])))
+		b.WriteString(field[1])
+	}
+	if d != nil {
+		title := "Environment"
+		if !m.envReveal {
+			title += " (secrets hidden)"
+		}
+		b.WriteString("\n\n" + m.styles.systemInfo.Render(title) + "\n")
+		b.WriteString(m.renderEnv())
+	}
+
+	style := m.styles.processTable.Padding(0, 1)
+	if m.width > 0 {
+		style = style.Width(m.width - 2)
+	}
+	return style.Render(b.String())
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// A process counts as changed once its CPU% or MEM% has moved by at
+	// least this many percentage points since the baseline
+	diffCPUThreshold = 5.0
+	diffMemThreshold = 1.0
+
+	// diffMaxRows caps each section of the diff view
+	diffMaxRows = 10
+)
+
+// processKey identifies a process across samples. The start time tells a
+// recycled PID apart from the process that used it before.
+type processKey struct {
+	pid       int32
+	startTime int64
+}
+
+func keyOf(proc ProcessInfo) processKey {
+	return processKey{pid: proc.PID, startTime: proc.StartTime}
+}
+
+// processChange is a process whose usage moved past the diff thresholds.
+type processChange struct {
+	before, after ProcessInfo
+}
+
+// processDiff is the difference between a baseline and the current sample.
+type processDiff struct {
+	added   []ProcessInfo
+	removed []ProcessInfo
+	changed []processChange
+}
+
+// diffProcesses compares the current process list with the baseline.
+// Changed processes are ordered by how far their CPU% moved.
+func diffProcesses(baseline, current []ProcessInfo) processDiff {
+	var d processDiff
+
+	before := make(map[processKey]ProcessInfo, len(baseline))
+	for _, proc := range baseline {
+		before[keyOf(proc)] = proc
+	}
+
+	seen := make(map[processKey]bool, len(current))
+	for _, proc := range current {
+		key := keyOf(proc)
+		seen[key] = true
+
+		old, ok := before[key]
+		if !ok {
+			d.added = append(d.added, proc)
+			continue
+		}
+		if abs(proc.CPUPerc-old.CPUPerc) >= diffCPUThreshold ||
+			abs(float64(proc.MemPerc-old.MemPerc)) >= diffMemThreshold {
+			d.changed = append(d.changed, processChange{before: old, after: proc})
+		}
+	}
+	for _, proc := range baseline {
+		if !seen[keyOf(proc)] {
+			d.removed = append(d.removed, proc)
+		}
+	}
+
+	sort.Slice(d.added, func(i, j int) bool { return d.added[i].PID < d.added[j].PID })
+	sort.Slice(d.removed, func(i, j int) bool { return d.removed[i].PID < d.removed[j].PID })
+	sort.Slice(d.changed, func(i, j int) bool {
+		di := abs(d.changed[i].after.CPUPerc - d.changed[i].before.CPUPerc)
+		dj := abs(d.changed[j].after.CPUPerc - d.changed[j].before.CPUPerc)
+		return di > dj
+	})
+	return d
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// captureBaseline remembers the current process list for the diff view.
+func (m *model) captureBaseline() {
+	m.baseline = append([]ProcessInfo(nil), m.stats.processInfo...)
+	m.baselineAt = m.stats.sampledAt
+	m.notice = fmt.Sprintf("Baseline captured (%d processes)", len(m.baseline))
+}
+
+// openDiff switches to the diff view, or asks for a baseline first.
+func (m model) openDiff() (tea.Model, tea.Cmd) {
+	if m.baseline == nil {
+		m.notice = "No baseline yet: press [b] to capture one, then [d] to compare"
+		return m, nil
+	}
+	m.mode = viewDiff
+	return m, nil
+}
+
+// updateDiff captures key presses while the diff view is open.
+func (m model) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.notice = ""
+	switch msg.String() {
+	case "esc", "d":
+		m.mode = viewTable
+	case "b":
+		m.captureBaseline()
+	case "q":
+		return m.requestQuit()
+	case "ctrl+c":
+		return m.quit()
+	}
+	return m, nil
+}
+
+// renderDiff renders the processes that appeared, disappeared or changed
+// since the baseline.
+func (m model) renderDiff() string {
+	d := diffProcesses(m.baseline, m.stats.processInfo)
+
+	var b strings.Builder
+	b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("Changes since baseline at %s (%s ago)",
+		m.baselineAt.Format("15:04:05"), formatDuration(time.Since(m.baselineAt)))))
+	b.WriteString("\n")
+
+	section := func(title string, n int, line func(i int) string) {
+		b.WriteString(fmt.Sprintf("\n%s (%d)\n", title, n))
+		for i := 0; i < n && i < diffMaxRows; i++ {
+			b.WriteString(line(i))
+			b.WriteString("\n")
+		}
+		if n > diffMaxRows {
+			b.WriteString(fmt.Sprintf("  … and %d more\n", n-diffMaxRows))
+		}
+	}
+
+	section("Appeared", len(d.added), func(i int) string {
+		proc := d.added[i]
+		return m.styles.cpuLow.Render(fmt.Sprintf("+ %7d %-8s %5.1f%% cpu %5.1f%% mem  %s",
+			proc.PID, proc.User, proc.CPUPerc, proc.MemPerc, proc.Name))
+	})
+	section("Disappeared", len(d.removed), func(i int) string {
+		proc := d.removed[i]
+		return m.styles.error.Render(fmt.Sprintf("- %7d %-8s %5.1f%% cpu %5.1f%% mem  %s",
+			proc.PID, proc.User, proc.CPUPerc, proc.MemPerc, proc.Name))
+	})
+	section("Changed", len(d.changed), func(i int) string {
+		c := d.changed[i]
+		style := m.styles.cpuLow
+		if c.after.CPUPerc > c.before.CPUPerc || c.after.MemPerc > c.before.MemPerc {
+			style = m.styles.cpuMid
+		}
+		return style.Render(fmt.Sprintf("~ %7d %-8s %5.1f%% → %5.1f%% cpu %5.1f%% → %5.1f%% mem  %s",
+			c.after.PID, c.after.User, c.before.CPUPerc, c.after.CPUPerc,
+			c.before.MemPerc, c.after.MemPerc, c.after.Name))
+	})
+
+	return b.String()
+}
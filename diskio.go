@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskSample is a snapshot of the cumulative byte counters of each disk.
+type diskSample struct {
+	read  map[string]uint64 // keyed by device name
+	write map[string]uint64
+	at    time.Time
+}
+
+// diskRate is the throughput of one disk between two samples.
+type diskRate struct {
+	name        string
+	read, write float64 // bytes per second
+}
+
+// getDiskIO samples the I/O counters of the physical disks. Partitions and
+// devices stacked on other disks, such as LVM volumes, are left out so the
+// same bytes aren't counted twice.
+func getDiskIO() (*diskSample, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+	sample := &diskSample{
+		read:  make(map[string]uint64),
+		write: make(map[string]uint64),
+		at:    time.Now(),
+	}
+	for name, c := range counters {
+		if !physicalDisk(name) {
+			continue
+		}
+		sample.read[name] = c.ReadBytes
+		sample.write[name] = c.WriteBytes
+	}
+	return sample, nil
+}
+
+// diskRates derives the throughput of each disk in cur since prev, sorted
+// by name. Disks missing from prev, as on the first sample, or whose
+// counters went backwards read as idle.
+func diskRates(prev, cur *diskSample) []diskRate {
+	var elapsed float64
+	if prev != nil {
+		elapsed = cur.at.Sub(prev.at).Seconds()
+	}
+	rates := make([]diskRate, 0, len(cur.read))
+	for name, read := range cur.read {
+		rate := diskRate{name: name}
+		write := cur.write[name]
+		if elapsed > 0 {
+			prevRead, ok := prev.read[name]
+			prevWrite := prev.write[name]
+			if ok && read >= prevRead && write >= prevWrite {
+				rate.read = float64(read-prevRead) / elapsed
+				rate.write = float64(write-prevWrite) / elapsed
+			}
+		}
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].name < rates[j].name })
+	return rates
+}
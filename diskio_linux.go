@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// physicalDisk reports whether name is a whole disk rather than a
+// partition, a loop or RAM device, or a device mapper or md volume built
+// on other disks. Only whole disks have an entry in /sys/block, and
+// stacked devices list the disks beneath them in its slaves directory.
+func physicalDisk(name string) bool {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "zram") {
+		return false
+	}
+	dir := filepath.Join("/sys/block", name)
+	if _, err := os.Stat(dir); err != nil {
+		return false
+	}
+	slaves, _ := os.ReadDir(filepath.Join(dir, "slaves"))
+	return len(slaves) == 0
+}
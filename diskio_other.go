@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// physicalDisk always reports true; other platforms don't list partitions
+// separately.
+func physicalDisk(name string) bool {
+	return true
+}
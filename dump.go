@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processDump is everything written for a single process from the detail
+// view. Fields that can't be read are left out, with the reason in Errors.
+type processDump struct {
+	Timestamp   time.Time               `json:"timestamp"`
+	Process     ProcessInfo             `json:"process"`
+	Exe         string                  `json:"exe,omitempty"`
+	GoBuild     string                  `json:"goBuild,omitempty"`
+	Cwd         string                  `json:"cwd,omitempty"`
+	Environ     []string                `json:"environ,omitempty"`
+	OpenFiles   []string                `json:"openFiles,omitempty"`
+	ThreadIDs   []int32                 `json:"threadIds,omitempty"`
+	IO          *process.IOCountersStat `json:"io,omitempty"`
+	Connections []net.ConnectionStat    `json:"connections,omitempty"`
+	Errors      map[string]string       `json:"errors,omitempty"`
+}
+
+// dumpMsg reports where a process dump was written, or why it failed.
+type dumpMsg struct {
+	path string
+	err  error
+}
+
+// dumpProcess gathers the full details of proc, as the detail view does
+// plus its open files, threads, I/O counters and connections, and writes
+// them as JSON to a timestamped file in the current directory. Secret
+// looking environment variables are hidden unless redact is false.
+func dumpProcess(proc ProcessInfo, redact bool) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		d := readDetail(proc.PID, nil)
+		dump := processDump{
+			Timestamp: now,
+			Process:   proc,
+			Exe:       d.exe,
+			GoBuild:   d.goBuild,
+			Cwd:       d.cwd,
+			Environ:   d.env,
+			Errors:    make(map[string]string),
+		}
+		if d.envErr != nil {
+			dump.Errors["environ"] = d.envErr.Error()
+		} else if redact {
+			dump.Environ = make([]string, len(d.env))
+			for i, entry := range d.env {
+				dump.Environ[i] = redactEnv(entry)
+			}
+		}
+
+		if p, err := process.NewProcess(proc.PID); err != nil {
+			dump.Errors["process"] = err.Error()
+		} else {
+			if files, err := p.OpenFiles(); err == nil {
+				for _, f := range files {
+					dump.OpenFiles = append(dump.OpenFiles, f.Path)
+				}
+			} else {
+				dump.Errors["openFiles"] = err.Error()
+			}
+			if threads, err := p.Threads(); err == nil {
+				for tid := range threads {
+					dump.ThreadIDs = append(dump.ThreadIDs, tid)
+				}
+				slices.Sort(dump.ThreadIDs)
+			} else {
+				dump.Errors["threads"] = err.Error()
+			}
+			if io, err := p.IOCounters(); err == nil {
+				dump.IO = io
+			} else {
+				dump.Errors["io"] = err.Error()
+			}
+			if conns, err := p.Connections(); err == nil {
+				dump.Connections = conns
+			} else {
+				dump.Errors["connections"] = err.Error()
+			}
+		}
+
+		path := fmt.Sprintf("xtop-%d-%s.json", proc.PID, now.Format("20060102-150405"))
+		data, err := json.MarshalIndent(dump, "", "  ")
+		if err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0o600)
+		}
+		if err != nil {
+			return dumpMsg{err: err}
+		}
+		return dumpMsg{path: path}
+	}
+}
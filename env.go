@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPanelHeight is how many environment variables the detail view shows
+// at once; the rest are reached by scrolling.
+const envPanelHeight = 10
+
+// secretWords mark environment variables whose values are hidden unless
+// revealed, matched against the upper-cased name.
+var secretWords = []string{"TOKEN", "PASSWORD", "PASSWD", "SECRET", "KEY", "CREDENTIAL"}
+
+// redactEnv hides the value of a NAME=value entry whose name looks like it
+// holds a secret.
+func redactEnv(entry string) string {
+	name, _, ok := strings.Cut(entry, "=")
+	if !ok {
+		return entry
+	}
+	upper := strings.ToUpper(name)
+	for _, word := range secretWords {
+		if strings.Contains(upper, word) {
+			return name + "=••••••"
+		}
+	}
+	return entry
+}
+
+// scrollEnv moves the environment panel by delta lines, keeping a full
+// panel on screen.
+func (m *model) scrollEnv(delta int) {
+	if m.detail == nil {
+		return
+	}
+	last := max(len(m.detail.env)-envPanelHeight, 0)
+	m.envScroll = min(max(m.envScroll+delta, 0), last)
+}
+
+// renderEnv renders the visible part of the detail process's environment,
+// or why it couldn't be read.
+func (m model) renderEnv() string {
+	d := m.detail
+	if d.envErr != nil {
+		if errors.Is(d.envErr, os.ErrPermission) {
+			return m.styles.error.Render("Environment unreadable: permission denied (another user's process needs root)")
+		}
+		return m.styles.error.Render(fmt.Sprintf("Environment unreadable: %v", d.envErr))
+	}
+	if len(d.env) == 0 {
+		return "(empty)"
+	}
+
+	start := min(m.envScroll, max(len(d.env)-envPanelHeight, 0))
+	end := min(start+envPanelHeight, len(d.env))
+	lines := make([]string, 0, end-start+1)
+	for _, entry := range d.env[start:end] {
+		if !m.envReveal {
+			entry = redactEnv(entry)
+		}
+		lines = append(lines, entry)
+	}
+	if len(d.env) > envPanelHeight {
+		lines = append(lines, m.styles.systemInfo.Render(
+			fmt.Sprintf("%d-%d of %d", start+1, end, len(d.env))))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// snapshotSystem is the system-wide part of an exported snapshot.
+type snapshotSystem struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Uptime     string    `json:"uptime,omitempty"`
+	Load1      float64   `json:"load1"`
+	Load5      float64   `json:"load5"`
+	Load15     float64   `json:"load15"`
+	CPUPercent []float64 `json:"cpuPercent"`
+	MemUsed    uint64    `json:"memUsed"`
+	MemTotal   uint64    `json:"memTotal"`
+}
+
+type snapshot struct {
+	System    snapshotSystem `json:"system"`
+	Processes []ProcessInfo  `json:"processes"`
+}
+
+func newSnapshotSystem(stats systemStats, now time.Time) snapshotSystem {
+	sys := snapshotSystem{
+		Timestamp:  now,
+		CPUPercent: stats.cpuPercent,
+	}
+	if stats.uptime > 0 {
+		sys.Uptime = formatDuration(stats.uptime)
+	}
+	if stats.loadAvg != nil {
+		sys.Load1 = stats.loadAvg.Load1
+		sys.Load5 = stats.loadAvg.Load5
+		sys.Load15 = stats.loadAvg.Load15
+	}
+	if stats.memStats != nil {
+		sys.MemUsed = stats.memStats.Used
+		sys.MemTotal = stats.memStats.Total
+	}
+	return sys
+}
+
+// exportSnapshot writes the system stats and processInfo to a timestamped
+// file in the current directory and returns its path. format is "csv" or
+// "json".
+func exportSnapshot(stats systemStats, processInfo []ProcessInfo, format string) (string, error) {
+	now := time.Now()
+	path := fmt.Sprintf("xtop-%s.%s", now.Format("20060102-150405"), format)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sys := newSnapshotSystem(stats, now)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(snapshot{System: sys, Processes: processInfo})
+	case "csv":
+		err = writeSnapshotCSV(f, sys, processInfo)
+	default:
+		err = fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// writeSnapshotCSV writes the system stats as key/value rows, followed by
+// a blank row and the process table. Process figures that couldn't be
+// read, which are -1 in memory, are left blank.
+func writeSnapshotCSV(f *os.File, sys snapshotSystem, processInfo []ProcessInfo) error {
+	w := csv.NewWriter(f)
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	formatKnown := func(v float64) string {
+		if v < 0 {
+			return ""
+		}
+		return formatFloat(v)
+	}
+
+	records := [][]string{
+		{"timestamp", sys.Timestamp.Format(time.RFC3339)},
+		{"uptime", sys.Uptime},
+		{"load", formatFloat(sys.Load1), formatFloat(sys.Load5), formatFloat(sys.Load15)},
+		{"mem_used", strconv.FormatUint(sys.MemUsed, 10)},
+		{"mem_total", strconv.FormatUint(sys.MemTotal, 10)},
+	}
+	cpuRow := []string{"cpu_percent"}
+	for _, usage := range sys.CPUPercent {
+		cpuRow = append(cpuRow, formatFloat(usage))
+	}
+	records = append(records, cpuRow, nil)
+
+	records = append(records, []string{
+		"pid", "ppid", "user", "tty", "name", "cmdline", "cpu_percent", "cpu_avg",
+		"mem_percent", "mem_rss", "swap", "threads", "fds", "connections", "cgroup", "disk_read_bps", "disk_write_bps", "nice", "status", "start_time",
+	})
+	for _, proc := range processInfo {
+		records = append(records, []string{
+			strconv.Itoa(int(proc.PID)),
+			strconv.Itoa(int(proc.PPID)),
+			proc.User,
+			proc.TTY,
+			proc.Name,
+			proc.Cmdline,
+			formatFloat(proc.CPUPerc),
+			formatFloat(proc.CPUAvg),
+			formatFloat(float64(proc.MemPerc)),
+			strconv.FormatUint(proc.MemRSS, 10),
+			csvCount(proc.Swap),
+			strconv.Itoa(int(proc.Threads)),
+			csvCount(proc.NumFDs),
+			csvCount(proc.Conns),
+			proc.Cgroup,
+			formatKnown(proc.DiskReadBps),
+			formatKnown(proc.DiskWriteBps),
+			strconv.Itoa(int(proc.Nice)),
+			proc.Status,
+			strconv.FormatInt(proc.StartTime, 10),
+		})
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// csvCount formats a count for the CSV export, left blank rather than -1
+// when it wasn't or couldn't be collected.
+func csvCount[T int32 | int64](n T) string {
+	if n < 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(n), 10)
+}
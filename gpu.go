@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpuTimeout bounds how long a single nvidia-smi query may take so a hung
+// driver can't stall the refresh loop.
+const gpuTimeout = time.Second
+
+// gpuStat is the utilization of a single NVIDIA GPU. Memory is in bytes.
+type gpuStat struct {
+	index    int
+	util     float64
+	memUsed  uint64
+	memTotal uint64
+}
+
+// getGPUStats queries nvidia-smi for per-GPU utilization. It returns nil if
+// nvidia-smi isn't installed or its output can't be read.
+func getGPUStats() []gpuStat {
+	path, err := exec.LookPath("nvidia-smi")
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gpuTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path,
+		"--query-gpu=utilization.gpu,memory.used,memory.total",
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil
+	}
+
+	return parseGPUStats(string(out))
+}
+
+// parseGPUStats parses nvidia-smi CSV output with one GPU per line, in the
+// form "util, memUsedMiB, memTotalMiB". Malformed lines are skipped.
+func parseGPUStats(out string) []gpuStat {
+	var gpus []gpuStat
+	for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+
+		util, err1 := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		used, err2 := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 64)
+		total, err3 := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		gpus = append(gpus, gpuStat{
+			index:    i,
+			util:     util,
+			memUsed:  used * 1024 * 1024,
+			memTotal: total * 1024 * 1024,
+		})
+	}
+	return gpus
+}
@@ -0,0 +1,55 @@
+package main
+
+import "strings"
+
+// historySize is how many samples a history keeps before dropping the
+// oldest.
+const historySize = 60
+
+// sparkTicks are the block characters used to draw a sparkline, from
+// lowest to highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// history is a fixed-size ring of the most recent samples of a metric.
+type history struct {
+	samples []float64
+	start   int
+}
+
+// add appends v, overwriting the oldest sample once the history is full.
+func (h *history) add(v float64) {
+	if len(h.samples) < historySize {
+		h.samples = append(h.samples, v)
+		return
+	}
+	h.samples[h.start] = v
+	h.start = (h.start + 1) % historySize
+}
+
+// last returns up to n of the most recent samples, oldest first.
+func (h *history) last(n int) []float64 {
+	ordered := append(append([]float64{}, h.samples[h.start:]...), h.samples[:h.start]...)
+	if n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// sparkline draws values as a row of block characters scaled so that max
+// is a full block. Values above max are drawn as full blocks.
+func sparkline(values []float64, max float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(len(sparkTicks)-1))
+		}
+		if i < 0 {
+			i = 0
+		} else if i >= len(sparkTicks) {
+			i = len(sparkTicks) - 1
+		}
+		b.WriteRune(sparkTicks[i])
+	}
+	return b.String()
+}
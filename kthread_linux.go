@@ -0,0 +1,7 @@
+package main
+
+// isKernelThread reports whether proc is a kernel thread: kthreadd itself
+// (PID 2) or one of the threads it spawns.
+func isKernelThread(proc ProcessInfo) bool {
+	return proc.PID == 2 || proc.PPID == 2
+}
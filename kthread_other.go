@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// isKernelThread always reports false; kernel threads are only listed as
+// processes on Linux.
+func isKernelThread(proc ProcessInfo) bool {
+	return false
+}
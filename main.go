@@ -1,345 +1,3139 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
-var (
-	// Styles
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("15")).
-			Background(lipgloss.Color("57")).
-			Padding(0, 1)
-
-	systemInfoStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("10"))
-
-	processTableStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color("240"))
+const (
+	defaultInterval = 2 * time.Second
+	minInterval     = 100 * time.Millisecond
+	intervalStep    = 500 * time.Millisecond
+
+	defaultMaxRows = 50
+	maxRowsStep    = 10
+
+	cpuBarWidth = 10
+	// The total CPU bar is wider so it stands out from the per-core ones
+	totalBarWidth = 2 * cpuBarWidth
+	// Width of one per-core cell: "NN " label, "[bar]" and " 100.0%" plus spacing
+	cpuCellWidth = 3 + cpuBarWidth + 2 + 7 + 2
+	// Cores beyond this many grid lines are summed up on one line
+	maxCPUGridLines = 4
+
+	// Processes are read by up to maxProcWorkers goroutines at once, and
+	// those not read within procReadTimeout are left out of the refresh
+	maxProcWorkers  = 16
+	procReadTimeout = 2 * time.Second
+
+	// Width of the process state bar under the task summary
+	stateBarWidth = 30
+
+	// Processes younger than this are highlighted, so crash loops and
+	// other short-lived processes stand out
+	youngAge = 2 * time.Second
+
+	// Below narrowWidth header labels are abbreviated, and below
+	// compactWidth the system info collapses into a single line.
+	narrowWidth  = 100
+	compactWidth = 60
+
+	minTableHeight = 3
+
+	// The header summarizes this many of the heaviest CPU users, with
+	// names cut to topNameLen runes
+	topCount   = 3
+	topNameLen = 15
+
+	// Usernames are cut to this many bytes to fit the USER column
+	maxUserLen = 8
+
+	// Processes below both thresholds count as idle for --hide-idle
+	idleCPUPercent = 0.05
+	idleMemPercent = 0.1
+
+	// A process seen in uninterruptible sleep on this many refreshes in a
+	// row is flagged as stuck in the header
+	stuckTicks = 3
+
+	// cpuAvgWeight is how much each new CPU% reading counts towards the
+	// smoothed AVG% figure; the rest carries over from earlier readings
+	cpuAvgWeight = 0.3
 )
 
+// statusLegend explains the process states in the STATUS column, which
+// some platforms report as single letters.
+const statusLegend = "States: R running • S sleeping • D disk wait • I idle • T stopped • Z zombie (exited, not yet reaped by its parent)"
+
+// columnSortKeys maps table column titles to the sort key used when the
+// header is clicked. Columns without an entry can't be sorted.
+var columnSortKeys = map[string]string{
+	"PID":     "pid",
+	"CPU%":    "cpu",
+	"AVG%":    "cpuavg",
+	"MEM%":    "memory",
+	"RES":     "memrss",
+	"SWAP":    "swap",
+	"THR":     "threads",
+	"FD":      "fds",
+	"CONN":    "conns",
+	"DISK R":  "diskread",
+	"DISK W":  "diskwrite",
+	"UPTIME":  "uptime",
+	"TIME+":   "time",
+	"COMMAND": "name",
+}
+
+// historyRanges are the sparkline lengths, in samples, cycled by the
+// history key.
+var historyRanges = []int{15, 30, historySize}
+
 type tickMsg time.Time
 type systemStats struct {
 	uptime      time.Duration
 	loadAvg     *load.AvgStat
 	cpuPercent  []float64
+	cpuTotal    float64 // whole machine, set along with cpuPercent
+	temps       []host.TemperatureStat
+	battery     *batteryStat // nil without a battery
 	memStats    *mem.VirtualMemoryStat
+	swapStats   *mem.SwapMemoryStat
+	diskUsage   []*disk.UsageStat
+	netIO       *netSample
+	diskIO      *diskSample
+	gpus        []gpuStat
+	psi         []psiStat
 	processes   []*process.Process
 	processInfo []ProcessInfo
+	sampledAt   time.Time
+	heapAlloc   uint64 // xtop's own Go heap
+
+	// errs records why each unavailable subsystem couldn't be collected,
+	// keyed by a human-readable name such as "process list".
+	errs map[string]error
+}
+
+// failedSubsystems returns the names of the subsystems that couldn't be
+// collected, in a stable order.
+func (s systemStats) failedSubsystems() []string {
+	names := make([]string, 0, len(s.errs))
+	for name := range s.errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// netSample is a snapshot of the cumulative network byte counters.
+type netSample struct {
+	bytesSent uint64
+	bytesRecv uint64
+	at        time.Time
 }
 
 type ProcessInfo struct {
 	PID     int32
+	PPID    int32
 	Name    string
-	CPUPerc float64
+	Cmdline string
+	CPUPerc float64 // share of one core, so up to 100 per core
+	CPUAvg  float64 // CPUPerc smoothed over recent refreshes
 	MemPerc float32
+	MemRSS  uint64
+	Threads int32
+	Nice    int32
+	NumFDs  int32 // -1 when unavailable
 	Status  string
 	User    string
+	TTY     string // controlling terminal such as "pts/0", or "?" for none
+
+	// StartTime is the creation time in epoch milliseconds, or 0 if unknown
+	StartTime int64
+
+	// DiskReadBps and DiskWriteBps are disk I/O rates in bytes per second,
+	// or -1 when the process's I/O counters can't be read.
+	DiskReadBps  float64
+	DiskWriteBps float64
+	// Conns is the number of open network connections, or -1 when they
+	// weren't or couldn't be counted. Only collected with --connections.
+	Conns int32
+	// Swap is how many bytes of the process are swapped out, or -1 when it
+	// couldn't be read. Only collected with --swap.
+	Swap int64
+	// Cgroup is the short ID of the container the process runs in, or
+	// else its systemd slice or unit; empty for processes in the root
+	// cgroup. Only collected with --cgroups.
+	Cgroup string
+
+	// tgid is the thread group, i.e. owning process, of the entry
+	tgid int32
+	// sid is the session the process belongs to, or 0 if unknown
+	sid int32
+	// members is how many processes a session or command row stands for
+	members int
+
+	// The cumulative counters below are used to derive CPUPerc and the
+	// disk rates from the difference between two samples.
+	cpuTime    float64
+	readBytes  uint64
+	writeBytes uint64
+	ioOK       bool
+}
+
+// processSample holds the cumulative counters of a process from the
+// previous refresh, along with its start time to spot a reused PID.
+type processSample struct {
+	cpuTime    float64
+	readBytes  uint64
+	writeBytes uint64
+	ioOK       bool
+	startTime  int64
+	cpuAvg     float64
+	sampledAt  time.Time
+}
+
+// uptime returns how long the process has been running, or 0 when its
+// start time is unknown.
+func (p ProcessInfo) uptime(now time.Time) time.Duration {
+	if p.StartTime == 0 {
+		return 0
+	}
+	return now.Sub(time.UnixMilli(p.StartTime))
+}
+
+// cpuTemperature picks a single CPU temperature from the sensor readings,
+// preferring a package-level sensor and otherwise averaging all non-zero
+// readings. ok is false when no sensor reported a temperature.
+func cpuTemperature(temps []host.TemperatureStat) (celsius float64, ok bool) {
+	for _, t := range temps {
+		key := strings.ToLower(t.SensorKey)
+		if t.Temperature > 0 && (strings.Contains(key, "package") || strings.Contains(key, "tctl")) {
+			return t.Temperature, true
+		}
+	}
+
+	var sum float64
+	var n int
+	for _, t := range temps {
+		if t.Temperature > 0 {
+			sum += t.Temperature
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// taskCounts summarizes the process list by state.
+type taskCounts struct {
+	total    int
+	threads  int
+	running  int
+	sleeping int
+	stopped  int
+	zombie   int
+	blocked  int // in uninterruptible sleep, also counted as sleeping
+}
+
+func countTasks(processInfo []ProcessInfo) taskCounts {
+	var counts taskCounts
+	for _, proc := range processInfo {
+		counts.total++
+		counts.threads += int(proc.Threads)
+		switch statusCategory(proc.Status) {
+		case "running":
+			counts.running++
+		case "sleeping":
+			counts.sleeping++
+		case "blocked":
+			counts.sleeping++
+			counts.blocked++
+		case "stopped":
+			counts.stopped++
+		case "zombie":
+			counts.zombie++
+		}
+	}
+	return counts
+}
+
+// statusCategory maps a process status, either a full gopsutil name such as
+// "sleep" or a single ps-style letter such as "S", to running, sleeping,
+// blocked (uninterruptible sleep), stopped or zombie. Unrecognized states
+// return "".
+func statusCategory(status string) string {
+	switch strings.ToLower(status) {
+	case "running", "r":
+		return "running"
+	case "sleep", "idle", "wait", "lock", "s", "i", "w", "l":
+		return "sleeping"
+	case "blocked", "d", "u":
+		return "blocked"
+	case "stop", "t":
+		return "stopped"
+	case "zombie", "z":
+		return "zombie"
+	}
+	return ""
+}
+
+// options holds the startup settings taken from the config file and
+// command line.
+type options struct {
+	sortBy    string
+	ascending bool
+	thenBy    string // tiebreak sort column
+	interval  time.Duration
+	maxRows   int           // 0 means no limit
+	sortEvery time.Duration // 0 re-sorts on every refresh
+
+	exportFormat string
+	noRedact     bool
+	precision    int    // decimals shown for CPU%
+	memUnit      string // mb, gb or auto for header memory figures
+	showGPU      bool
+	showPSI      bool
+	showConns    bool
+	showCgroups  bool
+	showSwap     bool
+	columns      []string // nil for the default set
+	watch        []string // process names to highlight
+	hidePanels   []string // header panels left out
+	user         string
+	filterRe     *regexp.Regexp
+	pids         []int32
+	pidKids      bool
+	metrics      *metricsLog // opened from --log
+	alerts       alerts
+	once         bool
+	jsonStream   bool
+	confirmQuit  bool // q asks before quitting
+	noColor      bool
+	oneOff       map[string]bool // flags that override the config file for this run only
+
+	theme  string
+	themes map[string]Theme // defined in the config file
+	colors Theme            // config file overrides on top of theme
+
+	hideIdle   bool
+	hideKernel bool
+	compact    bool
+}
+
+// killRequest is a signal waiting for the user to confirm it.
+type killRequest struct {
+	pid     int32
+	name    string
+	sig     syscall.Signal
+	sigName string // such as "SIGTERM"
+	// Running as root a second confirmation is needed, since any process
+	// can be signalled
+	confirmed bool
+}
+
+// actionResultMsg reports the outcome of an action taken on a process,
+// such as sending a signal or changing its priority.
+type actionResultMsg struct {
+	err error
+}
+
+// signalMsg reports that xtop was asked to stop by a signal, such as
+// SIGTERM from a supervisor.
+type signalMsg struct {
+	sig os.Signal
 }
 
 type model struct {
 	table      table.Model
+	ports      table.Model // listening ports view
+	portsErr   error
 	stats      systemStats
+	rows       []ProcessInfo
 	sortBy     string
+	thenBy     string
 	ascending  bool
+	interval   time.Duration
+	maxRows    int // rows per page, 0 for a single page
+	page       int // zero-based
+	pages      int
+	sortEvery  time.Duration // how often refreshes may reorder rows
+	sortedAt   time.Time
+	sortRank   map[int32]int // row order of each PID at the last sort
+	holdSort   bool          // keep the row order on this update
+	prevProc   map[int32]processSample
+	prevProcAt time.Time
+	newProcs   int // processes started since the previous sample
+	prevNet    *netSample
+	cpuHistory history
+	loadHist   history       // 1-minute load averages
+	topCPU     []ProcessInfo // heaviest CPU users, for the header
+	histRange  int
+	hidePanels []string
+	memDetail  bool // show the memory breakdown line
+	hideCores  bool // only the total CPU bar, no per-core bars
+	sockets    []int
+	netRecvBps float64
+	netSentBps float64
+	prevDisk   *diskSample
+	diskRates  []diskRate
+	perDisk    bool
+	blocked    map[int32]int // refreshes in a row each PID was in D state
+	width      int
+	height     int
+	confirm    *killRequest
+	picker     *signalPicker
+	askQuit    bool
+	quitting   bool // the quit prompt is open
+	mode       viewMode
+	detailProc ProcessInfo
+	detail     *processDetail
+	envScroll  int
+	envReveal  bool
+	noRedact   bool
+	editAff    bool // typing a new CPU affinity
+	affInput   string
+	baseline   []ProcessInfo // captured for the diff view
+	baselineAt time.Time
+	meta       *metaCache
+	metrics    *metricsLog
+	alerts     alerts
+	logErr     error // why metrics logging was turned off
+	filter     string
+	filtering  bool
+	filterRe   *regexp.Regexp
+	filterErr  error
+	user       string // only show this user's processes when set
+	me         string // who xtop runs as
+	root       bool   // effective user is root
+	mineOnly   bool   // only show processes owned by me
+	search     string
+	searching  bool
+	pids       []int32
+	pidKids    bool
+	showArgs   bool
+	treeView   bool
+	grouped    bool // threads folded into their process
+	sessions   bool // processes folded into their session
+	byName     bool // processes folded by command name
+	solaris    bool // CPU% divided by the number of CPUs
+	hideIdle   bool
+	hideKernel bool
+	visible    int // processes left after filtering, before the row cap
+	total      int // processes before filtering
+	paused     bool
+	stepping   bool  // a refresh was asked for while paused
+	follow     int32 // PID kept under the cursor, 0 when not following
+	exportFmt  string
+	precision  int
+	memUnit    string
+	showGPU    bool
+	showPSI    bool
+	showConns  bool
+	showCgroup bool
+	showSwap   bool
+	cols       []columnSpec
+	fullCols   []columnSpec
+	colOffset  int
+	columnList []string // as given by --columns or the config file
+	compact    bool
+	watch      []string
+	watchSeen  map[string]bool // watch entries running at the last refresh
+	notice     string
+	styles     styles
+	theme      string
+	themes     map[string]Theme
+	colors     Theme
+	oneOff     map[string]bool // see options, until changed interactively
+	noColor    bool
+	cfgMod     time.Time // when the config file was last changed, as of the last check
 	lastUpdate time.Time
 	err        error
 }
 
-func initialModel() model {
-	columns := []table.Column{
-		{Title: "PID", Width: 8},
-		{Title: "USER", Width: 10},
-		{Title: "CPU%", Width: 8},
-		{Title: "MEM%", Width: 8},
-		{Title: "STATUS", Width: 10},
-		{Title: "COMMAND", Width: 30},
+func initialModel(opts options) model {
+	fullCols := shownColumns(opts.columns, opts.showConns, opts.showCgroups, opts.showSwap)
+	cols := fullCols
+	if opts.compact {
+		cols = columnSpecs(compactColumns)
 	}
 
 	t := table.New(
-		table.WithColumns(columns),
+		table.WithColumns(tableColumns(0, cols)),
+		table.WithFocused(true),
+		table.WithHeight(15),
+		table.WithKeyMap(tableKeyMap()),
+	)
+
+	theme := resolveTheme(opts)
+	t.SetStyles(tableStyles(theme))
+
+	ports := table.New(
+		table.WithColumns(portColumns(0)),
 		table.WithFocused(true),
 		table.WithHeight(15),
+		table.WithKeyMap(tableKeyMap()),
+		table.WithStyles(tableStyles(theme)),
 	)
 
+	return model{
+		table:      t,
+		ports:      ports,
+		sortBy:     opts.sortBy,
+		thenBy:     opts.thenBy,
+		ascending:  opts.ascending,
+		interval:   opts.interval,
+		maxRows:    opts.maxRows,
+		sortEvery:  opts.sortEvery,
+		exportFmt:  opts.exportFormat,
+		noRedact:   opts.noRedact,
+		askQuit:    opts.confirmQuit,
+		precision:  opts.precision,
+		memUnit:    opts.memUnit,
+		showGPU:    opts.showGPU,
+		showPSI:    opts.showPSI,
+		showConns:  opts.showConns,
+		showCgroup: opts.showCgroups,
+		showSwap:   opts.showSwap,
+		cols:       cols,
+		fullCols:   fullCols,
+		compact:    opts.compact,
+		columnList: opts.columns,
+		watch:      opts.watch,
+		hidePanels: opts.hidePanels,
+		user:       shortUser(opts.user),
+		me:         currentUser(),
+		root:       os.Geteuid() == 0,
+		pids:       opts.pids,
+		pidKids:    opts.pidKids,
+		filter:     regexFilter(opts.filterRe),
+		filterRe:   opts.filterRe,
+		metrics:    opts.metrics,
+		alerts:     opts.alerts,
+		histRange:  historyRanges[len(historyRanges)-1],
+		hideIdle:   opts.hideIdle,
+		hideKernel: opts.hideKernel,
+		meta:       newMetaCache(),
+		styles:     newStyles(theme),
+		theme:      opts.theme,
+		themes:     opts.themes,
+		colors:     opts.colors,
+		noColor:    opts.noColor,
+		oneOff:     opts.oneOff,
+		cfgMod:     configModTime(),
+		sockets:    cpuSockets(),
+	}
+}
+
+// shownColumns resolves the columns named by --columns or the config file,
+// or the default set when keys is nil. Columns this platform can't fill in
+// are left out rather than shown empty.
+func shownColumns(keys []string, showConns, showCgroups, showSwap bool) []columnSpec {
+	if keys == nil {
+		keys = defaultColumns(showConns, showCgroups, showSwap)
+	}
+	caps := platformCapabilities()
+	keys = slices.DeleteFunc(slices.Clone(keys), func(key string) bool {
+		return !caps.supports(key)
+	})
+	return columnSpecs(keys)
+}
+
+// tableStyles returns the header and selected row styles of the process
+// and port tables in theme.
+func tableStyles(theme Theme) table.Styles {
 	s := table.DefaultStyles()
 	s.Header = s.Header.
 		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		BorderForeground(themeColor(theme.Border)).
 		BorderBottom(true).
 		Bold(false)
 	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
+		Foreground(themeColor(theme.SelectedFg)).
+		Background(themeColor(theme.SelectedBg)).
+		Reverse(theme.SelectedBg == "").
 		Bold(false)
-	t.SetStyles(s)
+	return s
+}
 
-	return model{
-		table:     t,
-		sortBy:    "cpu",
-		ascending: false,
+// tableKeyMap returns arrow and vim-style navigation for the process table.
+// The table's default single-letter paging keys (b, f, u, d, space) are left
+// out so they don't collide with xtop's own shortcuts.
+func tableKeyMap() table.KeyMap {
+	return table.KeyMap{
+		LineUp:       key.NewBinding(key.WithKeys("up", "k")),
+		LineDown:     key.NewBinding(key.WithKeys("down", "j")),
+		PageUp:       key.NewBinding(key.WithKeys("pgup")),
+		PageDown:     key.NewBinding(key.WithKeys("pgdown")),
+		HalfPageUp:   key.NewBinding(key.WithKeys("ctrl+u")),
+		HalfPageDown: key.NewBinding(key.WithKeys("ctrl+d")),
+		GotoTop:      key.NewBinding(key.WithKeys("home", "g")),
+		GotoBottom:   key.NewBinding(key.WithKeys("end", "G")),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(tickCmd(), updateStats())
+	return tea.Batch(tickCmd(m.interval), m.updateStats())
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
+func tickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
-func updateStats() tea.Cmd {
+func (m model) updateStats() tea.Cmd {
+	showGPU, showPSI, showConns, showCgroups, showSwap := m.showGPU, m.showPSI, m.showConns, m.showCgroup, m.showSwap
+	meta := m.meta
+
 	return func() tea.Msg {
-		stats := systemStats{}
+		return collectStats(showGPU, showPSI, showConns, showCgroups, showSwap, meta)
+	}
+}
 
-		// Get uptime
-		if hostInfo, err := host.Info(); err == nil {
-			stats.uptime = time.Duration(hostInfo.Uptime) * time.Second
-		}
+// collectStats gathers one round of system and process statistics. A
+// subsystem that fails is recorded in errs and otherwise left empty.
+// Static per-process fields are reused from meta where possible.
+func collectStats(showGPU, showPSI, showConns, showCgroups, showSwap bool, meta *metaCache) systemStats {
+	stats := systemStats{errs: make(map[string]error)}
+
+	// Get uptime
+	if hostInfo, err := host.Info(); err == nil {
+		stats.uptime = time.Duration(hostInfo.Uptime) * time.Second
+	} else {
+		stats.errs["uptime"] = err
+	}
 
-		// Get load average
-		if loadStats, err := load.Avg(); err == nil {
-			stats.loadAvg = loadStats
+	// Get load average
+	if loadStats, err := load.Avg(); err == nil {
+		stats.loadAvg = loadStats
+	} else {
+		stats.errs["load average"] = err
+	}
+
+	// Get CPU usage, per core and for the machine as a whole
+	if cpuPercs, err := cpu.Percent(0, true); err == nil {
+		stats.cpuPercent = cpuPercs
+		stats.cpuTotal = averageCPU(cpuPercs)
+		if total, err := cpu.Percent(0, false); err == nil && len(total) == 1 {
+			stats.cpuTotal = total[0]
 		}
+	} else {
+		stats.errs["CPU usage"] = err
+	}
+
+	// Get temperatures. Some sensors may fail while others succeed, so
+	// keep whatever readings came back.
+	if temps, _ := host.SensorsTemperatures(); len(temps) > 0 {
+		stats.temps = temps
+	}
+
+	// Get battery charge, absent on most desktops and servers
+	stats.battery = getBattery()
+
+	// Get memory stats
+	if memStats, err := mem.VirtualMemory(); err == nil {
+		stats.memStats = memStats
+	} else {
+		stats.errs["memory"] = err
+	}
+	if swapStats, err := mem.SwapMemory(); err == nil {
+		stats.swapStats = swapStats
+	} else {
+		stats.errs["swap"] = err
+	}
 
-		// Get CPU usage
-		if cpuPercs, err := cpu.Percent(0, true); err == nil {
-			stats.cpuPercent = cpuPercs
+	// Get network counters
+	if counters, err := net.IOCounters(false); err != nil {
+		stats.errs["network"] = err
+	} else if len(counters) > 0 {
+		stats.netIO = &netSample{
+			bytesSent: counters[0].BytesSent,
+			bytesRecv: counters[0].BytesRecv,
+			at:        time.Now(),
 		}
+	}
+
+	// Get disk I/O counters
+	if sample, err := getDiskIO(); err == nil {
+		stats.diskIO = sample
+	} else {
+		stats.errs["disk I/O"] = err
+	}
+
+	// Get disk usage
+	if usage, err := getDiskUsage(); err == nil {
+		stats.diskUsage = usage
+	} else {
+		stats.errs["disk usage"] = err
+	}
+
+	// Get GPU usage
+	if showGPU {
+		stats.gpus = getGPUStats()
+	}
 
-		// Get memory stats
-		if memStats, err := mem.VirtualMemory(); err == nil {
-			stats.memStats = memStats
+	// Get pressure stall information
+	if showPSI {
+		if psi, err := getPSI(); err == nil {
+			stats.psi = psi
+		} else {
+			stats.errs["pressure stall information"] = err
 		}
+	}
+
+	// Get xtop's own heap, for the footer
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	stats.heapAlloc = memStats.HeapAlloc
 
-		// Get processes
-		if processes, err := process.Processes(); err == nil {
-			stats.processes = processes
-			stats.processInfo = getProcessInfo(processes)
+	// Get processes
+	stats.sampledAt = time.Now()
+	if processes, err := process.Processes(); err == nil {
+		stats.processes = processes
+		var skipped int
+		stats.processInfo, skipped = getProcessInfo(processes, meta, showCgroups, showSwap)
+		if skipped > 0 {
+			stats.errs["some processes"] = fmt.Errorf("%d took longer than %s to read and were left out", skipped, procReadTimeout)
 		}
+	} else {
+		stats.errs["process list"] = err
+	}
 
-		return stats
+	// Get per-process network connections
+	if showConns && stats.processInfo != nil {
+		counts, err := connectionCounts()
+		if err != nil {
+			stats.errs["connections"] = err
+		}
+		for i := range stats.processInfo {
+			if err != nil {
+				stats.processInfo[i].Conns = -1
+			} else {
+				stats.processInfo[i].Conns = counts[stats.processInfo[i].PID]
+			}
+		}
 	}
+
+	return stats
 }
 
-func getProcessInfo(processes []*process.Process) []ProcessInfo {
-	var processInfo []ProcessInfo
+// getDiskUsage returns usage for each mounted physical partition, skipping
+// any that can't be read. It falls back to the root filesystem when the
+// partition list is unavailable, and only fails if that can't be read either.
+func getDiskUsage() ([]*disk.UsageStat, error) {
+	partitions, err := disk.Partitions(false)
+	if err != nil || len(partitions) == 0 {
+		usage, err := disk.Usage("/")
+		if err != nil {
+			return nil, err
+		}
+		return []*disk.UsageStat{usage}, nil
+	}
+
+	var usages []*disk.UsageStat
+	seen := make(map[string]bool)
+	for _, part := range partitions {
+		if seen[part.Mountpoint] {
+			continue
+		}
+		seen[part.Mountpoint] = true
 
-	for _, p := range processes {
-		if p == nil {
+		usage, err := disk.Usage(part.Mountpoint)
+		if err != nil || usage.Total == 0 {
 			continue
 		}
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
 
-		name, _ := p.Name()
-		if name == "" {
+// getProcessInfo reads the current state of each process, several at a
+// time, and returns them along with how many couldn't be read within
+// procReadTimeout. Static fields come from cache when the process was seen
+// before, and the cache is trimmed to the processes still running.
+// Processes whose read from an earlier refresh is still stuck are skipped
+// and counted as unread. The cgroup is only read when withCgroup is set,
+// and swap usage when withSwap is.
+func getProcessInfo(processes []*process.Process, cache *metaCache, withCgroup, withSwap bool) ([]ProcessInfo, int) {
+	live := make(map[int32]bool, len(processes))
+	jobs := make(chan int, len(processes))
+	busy := 0
+	for i, p := range processes {
+		if p == nil {
+			continue
+		}
+		live[p.Pid] = true
+		if !cache.startRead(p.Pid) {
+			busy++
 			continue
 		}
+		jobs <- i
+	}
+	close(jobs)
+	cache.retain(live)
+
+	// Workers stop reading processes once the time is up, and only clear
+	// the marks of the rest. A read that is stuck can't be interrupted, so
+	// its result is simply never collected; the buffered channel lets its
+	// worker finish without blocking.
+	ctx, cancel := context.WithTimeout(context.Background(), procReadTimeout)
+	defer cancel()
+	type result struct {
+		i    int
+		info ProcessInfo
+	}
+	pending := len(jobs)
+	results := make(chan result, pending)
+	workers := min(max(runtime.NumCPU(), 4), maxProcWorkers)
+	for range workers {
+		go func() {
+			for i := range jobs {
+				if ctx.Err() == nil {
+					results <- result{i, readProcess(processes[i], cache, withCgroup, withSwap)}
+				}
+				cache.endRead(processes[i].Pid)
+			}
+		}()
+	}
+
+	// Keep the order of processes, as if they were read one by one
+	infos := make([]ProcessInfo, len(processes))
+	read := make([]bool, len(processes))
+collect:
+	for pending > 0 {
+		select {
+		case r := <-results:
+			infos[r.i], read[r.i] = r.info, true
+			pending--
+		case <-ctx.Done():
+			break collect
+		}
+	}
 
-		cpuPerc, _ := p.CPUPercent()
-		memPerc, _ := p.MemoryPercent()
-		status, _ := p.Status()
-		username, _ := p.Username()
+	processInfo := make([]ProcessInfo, 0, len(processes)-pending)
+	for i, ok := range read {
+		if ok {
+			processInfo = append(processInfo, infos[i])
+		}
+	}
+	return processInfo, pending + busy
+}
 
-		info := ProcessInfo{
-			PID:     p.Pid,
-			Name:    name,
-			CPUPerc: cpuPerc,
-			MemPerc: memPerc,
-			Status:  status,
-			User:    username,
+// readProcess reads the current figures of p, taking the fields that don't
+// change from cache where it can.
+func readProcess(p *process.Process, cache *metaCache, withCgroup, withSwap bool) ProcessInfo {
+	// Without a creation time a recycled PID can't be told apart, so
+	// such processes are read in full every time
+	startTime, _ := p.CreateTime()
+	meta, ok := cache.get(p.Pid, startTime)
+	// exec() keeps the PID and start time, so the name is checked each time
+	// to catch a process that has since become another program
+	if ok {
+		if name, err := p.Name(); err == nil && name != "" && name != meta.name {
+			ok = false
+		}
+	}
+	if !ok {
+		meta = readProcessMeta(p, startTime, withCgroup)
+		if startTime != 0 {
+			cache.put(p.Pid, meta)
 		}
+	}
+
+	status, _ := p.Status()
+	ppid, _ := p.Ppid()
+	var cpuTime float64
+	if times, err := p.Times(); err == nil && times != nil {
+		cpuTime = times.User + times.System
+	}
+	// I/O counters usually need permission to read another user's process
+	var readBytes, writeBytes uint64
+	io, err := p.IOCounters()
+	ioOK := err == nil && io != nil
+	if ioOK {
+		readBytes, writeBytes = io.ReadBytes, io.WriteBytes
+	}
+	memPerc, _ := p.MemoryPercent()
+	var memRSS uint64
+	if memInfo, err := p.MemoryInfo(); err == nil && memInfo != nil {
+		memRSS = memInfo.RSS
+	}
+	numThreads, _ := p.NumThreads()
+	var nice int32
+	if raw, err := p.Nice(); err == nil {
+		nice = niceFromPriority(raw)
+	}
+	// NumFDs isn't implemented everywhere and needs permission to read
+	// another user's descriptors
+	numFDs, err := p.NumFDs()
+	if err != nil {
+		numFDs = -1
+	}
+	swap := int64(-1)
+	if withSwap {
+		swap = processSwap(p.Pid)
+	}
+
+	info := ProcessInfo{
+		PID:     p.Pid,
+		PPID:    ppid,
+		Name:    meta.name,
+		Cmdline: meta.cmdline,
+		MemPerc: memPerc,
+		MemRSS:  memRSS,
+		Threads: numThreads,
+		Nice:    nice,
+		NumFDs:  numFDs,
+		Conns:   -1, // counted for all processes at once, if at all
+		Swap:    swap,
+		Status:  status,
+		User:    meta.user,
+		TTY:     meta.tty,
+		Cgroup:  meta.cgroup,
+		tgid:    meta.tgid,
+		sid:     meta.sid,
+		cpuTime: cpuTime,
+
+		readBytes:  readBytes,
+		writeBytes: writeBytes,
+		ioOK:       ioOK,
+
+		StartTime: startTime,
+	}
+
+	info.User = shortUser(info.User)
+	return info
+}
+
+// readProcessMeta reads the fields of p that don't change while it runs.
+// Processes are rarely moved between cgroups, so the cgroup is treated as
+// one of them.
+func readProcessMeta(p *process.Process, createTime int64, withCgroup bool) processMeta {
+	name, _ := p.Name()
+	cmdline, _ := p.Cmdline()
+	username, _ := p.Username()
 
-		// Limit username length
-		if len(info.User) > 8 {
-			info.User = info.User[:8]
+	// Keep nameless processes such as zombies visible under a placeholder
+	if name == "" {
+		if fields := strings.Fields(cmdline); len(fields) > 0 {
+			name = filepath.Base(fields[0])
+		} else {
+			name = fmt.Sprintf("[%d]", p.Pid)
 		}
+	}
+
+	tgid, err := p.Tgid()
+	if err != nil || tgid == 0 {
+		tgid = p.Pid
+	}
+
+	// Processes rarely leave their session or change their terminal, so
+	// both are read only once too
+	sid, _ := sessionID(p.Pid)
+	tty := processTTY(p.Pid)
 
-		processInfo = append(processInfo, info)
+	var cgroup string
+	if withCgroup {
+		cgroup = processCgroup(p.Pid)
 	}
 
-	return processInfo
+	return processMeta{
+		createTime: createTime,
+		name:       name,
+		cmdline:    cmdline,
+		user:       username,
+		tgid:       tgid,
+		sid:        sid,
+		tty:        tty,
+		cgroup:     cgroup,
+	}
 }
 
+// Update handles msg and then resizes the table to the space left by
+// the header and footer, which any message may have changed.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if nm, ok := next.(model); ok {
+		nm.fitTable()
+		next = nm
+	}
+	return next, cmd
+}
+
+// fitTable gives the process and ports tables whatever height the header
+// and footer leave. It is set here rather than while rendering, since the
+// tables page and keep the cursor in view using their stored height.
+func (m *model) fitTable() {
+	if m.height <= 0 {
+		return
+	}
+	// The header ends in a newline, so its height already counts the line
+	// the table starts on; add one more for the table's border
+	used := lipgloss.Height(m.renderTop()) + lipgloss.Height(m.renderFooter()) + 1
+	rows := m.table.Height()
+	m.table.SetHeight(max(m.height-used, minTableHeight))
+	if m.table.Height() != rows {
+		// Pages hold as many rows as the table shows
+		m.updateTable()
+	}
+	// The ports table also has a title line
+	m.ports.SetHeight(max(m.height-used-1, minTableHeight))
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.quitting {
+			return m.updateQuit(msg)
+		}
+		if m.confirm != nil {
+			return m.updateConfirm(msg)
+		}
+		if m.picker != nil {
+			return m.updateSignalPicker(msg)
+		}
+		if m.mode == viewDetail {
+			return m.updateDetail(msg)
+		}
+		if m.mode == viewDiff {
+			return m.updateDiff(msg)
+		}
+		if m.mode == viewPorts {
+			return m.updatePorts(msg)
+		}
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		m.notice = ""
+
 		switch msg.String() {
-		case "q", "ctrl+c":
-			return m, tea.Quit
+		case "q":
+			return m.requestQuit()
+		case "ctrl+c":
+			return m.quit()
+		case "enter":
+			return m.openDetail()
+		case "b":
+			m.captureBaseline()
+		case "d":
+			return m.openDiff()
+		case "L":
+			return m.openPorts()
+		case "/":
+			m.filtering = true
+			return m, nil
+		case "?":
+			m.searching = true
+			return m, nil
+		case " ":
+			// Handled here so the table doesn't also treat space as page down
+			m.paused = !m.paused
+			if !m.paused {
+				return m, m.updateStats()
+			}
+			return m, nil
+		case "e":
+			path, err := exportSnapshot(m.stats, m.rows, m.exportFmt)
+			if err != nil {
+				m.err = fmt.Errorf("export failed: %w", err)
+			} else {
+				m.err = nil
+				m.notice = "Exported to " + path
+			}
+		case "a":
+			m.showArgs = !m.showArgs
+			m.updateTable()
+		case "u":
+			m.user = m.nextUser()
+			m.updateTable()
+		case "U":
+			if m.me == "" {
+				m.notice = "Couldn't look up the current user"
+				break
+			}
+			m.mineOnly = !m.mineOnly
+			m.updateTable()
+		case "h":
+			m.histRange = nextHistoryRange(m.histRange)
+		case "M":
+			m.memDetail = !m.memDetail
+		case "1":
+			m.hideCores = !m.hideCores
+		case "2", "3", "4", "5":
+			m.togglePanel(panelKeys[msg.String()])
+		case "v":
+			m.setCompact(!m.compact)
+		case "left", "right":
+			if len(m.cols) < len(m.baseColumns()) || m.colOffset > 0 {
+				if msg.String() == "left" {
+					m.colOffset--
+				} else {
+					m.colOffset++
+				}
+				m.layoutColumns()
+			}
+		case "E":
+			m.toggleColumn("time")
+		case "V":
+			m.toggleColumn("cpuavg")
+		case "D":
+			m.perDisk = !m.perDisk
+		case "t":
+			m.treeView = !m.treeView
+			m.updateTable()
+		case "A":
+			m.grouped = !m.grouped
+			m.updateTable()
+		case "P":
+			m.sessions = !m.sessions
+			m.byName = false
+			m.updateTable()
+		case "#":
+			m.byName = !m.byName
+			m.sessions = false
+			m.updateTable()
+		case "S":
+			// htop uses I, which here already hides idle processes
+			m.solaris = !m.solaris
+			m.updateTable()
+		case "I":
+			// h already cycles the history range, so hiding idle processes
+			// and kernel threads get a key each
+			m.hideIdle = !m.hideIdle
+			m.updateTable()
+		case "K":
+			m.hideKernel = !m.hideKernel
+			m.updateTable()
+		case "F":
+			// Not f, which sorts by open files
+			if m.follow != 0 {
+				m.follow = 0
+			} else if proc, ok := m.selectedProcess(); ok {
+				m.follow = proc.PID
+			}
+		case "esc":
+			m.search = ""
+			if m.filter != "" {
+				m.setFilter("")
+				m.updateTable()
+			}
+		case "<", ">":
+			if proc, ok := m.selectedTarget(); ok {
+				m.err = nil
+				delta := 1
+				if msg.String() == "<" {
+					delta = -1
+				}
+				return m, renice(proc, delta)
+			}
+		case "y":
+			if proc, ok := m.selectedProcess(); ok {
+				return m, copyToClipboard(fmt.Sprintf("PID %d", proc.PID), strconv.Itoa(int(proc.PID)))
+			}
+		case "Y":
+			if proc, ok := m.selectedProcess(); ok {
+				command := proc.Cmdline
+				if command == "" {
+					command = proc.Name
+				}
+				return m, copyToClipboard(fmt.Sprintf("command of PID %d", proc.PID), command)
+			}
+		case "x", "X":
+			// Not k and K, since k moves up as in vim
+			if proc, ok := m.selectedTarget(); ok {
+				m.err = nil
+				req := killRequest{pid: proc.PID, name: proc.Name, sig: syscall.SIGTERM, sigName: "SIGTERM"}
+				if msg.String() == "X" {
+					req.sig, req.sigName = syscall.SIGKILL, "SIGKILL"
+				}
+				m.confirm = &req
+			}
+			return m, nil
+		case "z":
+			return m.openSignalPicker()
 		case "c":
-			m.sortBy = "cpu"
-			m.ascending = !m.ascending
+			m.setSort("cpu")
 		case "m":
-			m.sortBy = "memory"
-			m.ascending = !m.ascending
+			m.setSort("memory")
+		case "r":
+			m.setSort("memrss")
+		case "T":
+			m.setSort("threads")
+		case "f":
+			m.setSort("fds")
+		case "C":
+			if m.showConns {
+				m.setSort("conns")
+			}
+		case "R":
+			m.setSort("diskread")
+		case "W":
+			m.setSort("diskwrite")
+		case "s":
+			m.setSort("uptime")
 		case "p":
-			m.sortBy = "pid"
-			m.ascending = !m.ascending
+			m.setSort("pid")
 		case "n":
-			m.sortBy = "name"
+			// With a search active, n steps through matches instead
+			if m.search != "" {
+				m.jumpToMatch(1)
+			} else {
+				m.setSort("name")
+			}
+		case "N":
+			if m.search != "" {
+				m.jumpToMatch(-1)
+			}
+		case "i":
 			m.ascending = !m.ascending
+			m.updateTable()
+		case "o":
+			// Re-sort now rather than waiting for --sort-interval
+			m.updateTable()
+		case "f5":
+			// Refresh now rather than waiting for the next tick; while
+			// paused this steps to a new snapshot. There's no r alias, as
+			// r sorts by RES.
+			m.stepping = m.paused
+			m.lastUpdate = time.Now()
+			return m, m.updateStats()
+		case "+", "=":
+			m.interval += intervalStep
+		case "-":
+			// Keep the interval on step boundaries without dropping below the floor
+			if m.interval-intervalStep >= minInterval {
+				m.interval -= intervalStep
+			} else {
+				m.interval = minInterval
+			}
+		case "]":
+			if m.maxRows > 0 {
+				m.maxRows += maxRowsStep
+				m.updateTable()
+			}
+		case "[":
+			m.maxRows = m.decreasedMaxRows()
+			m.updateTable()
+		case "pgdown", "pgup":
+			// With a single page these scroll the table as usual
+			if m.pages > 1 {
+				m.flipPage(msg.String() == "pgdown")
+				return m, nil
+			}
+		case "ctrl+d", "ctrl+u":
+			if m.pages > 1 && m.halfPage(msg.String() == "ctrl+d") {
+				return m, nil
+			}
 		}
 
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
+
 	case tickMsg:
+		if mod := configModTime(); !mod.Equal(m.cfgMod) {
+			m.cfgMod = mod
+			if !mod.IsZero() {
+				m.reloadConfig()
+			}
+		}
+		if m.paused {
+			return m, tickCmd(m.interval)
+		}
 		m.lastUpdate = time.Time(msg)
-		return m, tea.Batch(tickCmd(), updateStats())
+		return m, tea.Batch(tickCmd(m.interval), m.updateStats())
 
 	case systemStats:
+		// Drop samples that were already in flight when the view was
+		// paused, but not one asked for with the refresh key
+		if m.paused && !m.stepping {
+			return m, nil
+		}
+		m.stepping = false
+		// Keep showing the last process list rather than an empty table
+		// when it couldn't be refreshed
+		processErr := msg.errs["process list"]
+		if processErr != nil {
+			msg.processes = m.stats.processes
+			msg.processInfo = m.stats.processInfo
+		}
 		m.stats = msg
+		if processErr == nil {
+			m.updateProcessRates()
+		}
+		m.updateNetRates()
+		m.updateDiskRates()
+		if len(m.stats.cpuPercent) > 0 {
+			m.cpuHistory.add(m.stats.cpuTotal)
+		}
+		if m.stats.loadAvg != nil {
+			m.loadHist.add(m.stats.loadAvg.Load1)
+		}
+		m.topCPU = topConsumers(m.stats.processInfo, topCount)
+		m.updateBlocked()
+		m.holdSort = m.sortEvery > 0 && time.Since(m.sortedAt) < m.sortEvery
 		m.updateTable()
+		m.holdSort = false
+		if m.metrics != nil {
+			if err := m.metrics.write(m.stats); err != nil {
+				m.metrics.close()
+				m.metrics = nil
+				m.logErr = err
+			}
+		}
+		var alertCmd tea.Cmd
+		if m.alerts.update(m.stats) && m.alerts.beep {
+			alertCmd = bell
+		}
+		if m.checkWatched() && m.alerts.beep {
+			alertCmd = bell
+		}
+		if hook := m.alerts.hook(m.stats, time.Now()); hook != nil {
+			alertCmd = tea.Batch(alertCmd, hook)
+		}
+		switch m.mode {
+		case viewDetail:
+			return m, tea.Batch(alertCmd, m.refreshDetail())
+		case viewPorts:
+			return m, tea.Batch(alertCmd, fetchPorts())
+		}
+		return m, alertCmd
+
+	case detailMsg:
+		// Ignore details for a process that is no longer being shown
+		if m.mode == viewDetail && msg.pid == m.detailProc.PID {
+			d := processDetail(msg)
+			m.detail = &d
+		}
+		return m, nil
+
+	case portsMsg:
+		if m.mode == viewPorts {
+			m.setPorts(msg)
+		}
+		return m, nil
+
+	case actionResultMsg:
+		m.err = msg.err
+		return m, m.updateStats()
+
+	case signalMsg:
+		return m.quit()
+
+	case dumpMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("dump failed: %w", msg.err)
+		} else {
+			m.err = nil
+			m.notice = "Dumped to " + msg.path
+		}
+		return m, nil
+
+	case alertCommandMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Alert command failed: %v", msg.err)
+		}
+		return m, nil
+
+	case clipboardMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Couldn't copy %s: %v", msg.what, msg.err)
+		} else {
+			m.notice = "Copied " + msg.what
+		}
+		return m, nil
 
 	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
 		m.table.SetWidth(msg.Width - 4)
-		m.table.SetHeight(msg.Height - 12)
+		m.ports.SetWidth(msg.Width - 4)
+		m.ports.SetColumns(portColumns(m.width))
+		m.layoutColumns()
 	}
 
 	m.table, cmd = m.table.Update(msg)
 	return m, cmd
 }
 
-func (m *model) updateTable() {
-	// Sort processes
-	sort.Slice(m.stats.processInfo, func(i, j int) bool {
-		switch m.sortBy {
-		case "cpu":
-			if m.ascending {
-				return m.stats.processInfo[i].CPUPerc < m.stats.processInfo[j].CPUPerc
-			}
-			return m.stats.processInfo[i].CPUPerc > m.stats.processInfo[j].CPUPerc
-		case "memory":
-			if m.ascending {
-				return m.stats.processInfo[i].MemPerc < m.stats.processInfo[j].MemPerc
-			}
-			return m.stats.processInfo[i].MemPerc > m.stats.processInfo[j].MemPerc
-		case "pid":
-			if m.ascending {
-				return m.stats.processInfo[i].PID < m.stats.processInfo[j].PID
-			}
-			return m.stats.processInfo[i].PID > m.stats.processInfo[j].PID
-		case "name":
-			if m.ascending {
-				return m.stats.processInfo[i].Name < m.stats.processInfo[j].Name
-			}
-			return m.stats.processInfo[i].Name > m.stats.processInfo[j].Name
+// updateProcessRates fills in CPUPerc and the disk I/O rates from what each
+// process used since the previous sample, the way top does. Processes
+// without an earlier sample report 0 rather than their lifetime average.
+// CPUAvg is an exponentially weighted average of CPUPerc, started afresh
+// when a PID is reused. Processes that were too slow to read this time
+// keep their last sample, which the next refresh measures from.
+func (m *model) updateProcessRates() {
+	cur := make(map[int32]processSample, len(m.stats.processInfo))
+	m.newProcs = 0
+
+	for i := range m.stats.processInfo {
+		proc := &m.stats.processInfo[i]
+		prev, ok := m.prevProc[proc.PID]
+		// A PID that was reused since the last sample is new as well
+		if m.prevProc != nil && (!ok || proc.StartTime > m.prevProcAt.UnixMilli()) {
+			m.newProcs++
 		}
-		return false
-	})
+		// A reused PID's sample belongs to another process, so nothing is
+		// measured from it
+		reused := ok && prev.startTime != proc.StartTime
+		elapsed := m.stats.sampledAt.Sub(prev.sampledAt).Seconds()
+		ok = ok && !reused && elapsed > 0
 
-	// Convert to table rows
-	var rows []table.Row
-	for _, proc := range m.stats.processInfo {
-		if len(rows) >= 50 { // Limit to top 50 processes
-			break
+		if ok && proc.cpuTime >= prev.cpuTime {
+			proc.CPUPerc = (proc.cpuTime - prev.cpuTime) / elapsed * 100
+		} else {
+			proc.CPUPerc = 0
+		}
+		if ok {
+			proc.CPUAvg = cpuAvgWeight*proc.CPUPerc + (1-cpuAvgWeight)*prev.cpuAvg
+		} else {
+			proc.CPUAvg = proc.CPUPerc
 		}
 
-		// Truncate command name if too long
-		command := proc.Name
-		if len(command) > 28 {
-			command = command[:28] + ".."
+		cur[proc.PID] = processSample{
+			cpuTime:    proc.cpuTime,
+			readBytes:  proc.readBytes,
+			writeBytes: proc.writeBytes,
+			ioOK:       proc.ioOK,
+			startTime:  proc.StartTime,
+			cpuAvg:     proc.CPUAvg,
+			sampledAt:  m.stats.sampledAt,
 		}
 
-		rows = append(rows, table.Row{
-			strconv.Itoa(int(proc.PID)),
-			proc.User,
-			fmt.Sprintf("%.1f", proc.CPUPerc),
-			fmt.Sprintf("%.1f", proc.MemPerc),
-			proc.Status,
-			command,
-		})
+		switch {
+		case !proc.ioOK:
+			proc.DiskReadBps, proc.DiskWriteBps = -1, -1
+		case ok && prev.ioOK && proc.readBytes >= prev.readBytes && proc.writeBytes >= prev.writeBytes:
+			proc.DiskReadBps = float64(proc.readBytes-prev.readBytes) / elapsed
+			proc.DiskWriteBps = float64(proc.writeBytes-prev.writeBytes) / elapsed
+		default:
+			proc.DiskReadBps, proc.DiskWriteBps = 0, 0
+		}
 	}
 
-	m.table.SetRows(rows)
-}
+	for _, p := range m.stats.processes {
+		if p == nil {
+			continue
+		}
+		if _, ok := cur[p.Pid]; !ok {
+			if prev, ok := m.prevProc[p.Pid]; ok {
+				cur[p.Pid] = prev
+			}
+		}
+	}
 
-func (m model) View() string {
-	var b strings.Builder
+	m.prevProc = cur
+	m.prevProcAt = m.stats.sampledAt
+}
 
-	// Header
-	header := headerStyle.Render("GoTop - System Monitor")
-	b.WriteString(header + "\n\n")
+// updateNetRates derives throughput from the previous network sample,
+// dividing by the real time between samples rather than the tick interval.
+func (m *model) updateNetRates() {
+	cur := m.stats.netIO
+	if cur == nil {
+		return
+	}
 
-	// System info
-	if m.stats.uptime > 0 {
-		uptime := formatDuration(m.stats.uptime)
-		b.WriteString(systemInfoStyle.Render(fmt.Sprintf("Uptime: %s", uptime)))
-		b.WriteString("  ")
+	if prev := m.prevNet; prev != nil {
+		elapsed := cur.at.Sub(prev.at).Seconds()
+		if elapsed > 0 && cur.bytesRecv >= prev.bytesRecv && cur.bytesSent >= prev.bytesSent {
+			m.netRecvBps = float64(cur.bytesRecv-prev.bytesRecv) / elapsed
+			m.netSentBps = float64(cur.bytesSent-prev.bytesSent) / elapsed
+		}
 	}
+	m.prevNet = cur
+}
 
-	if m.stats.loadAvg != nil {
-		b.WriteString(systemInfoStyle.Render(fmt.Sprintf("Load: %.2f %.2f %.2f", 
-			m.stats.loadAvg.Load1, m.stats.loadAvg.Load5, m.stats.loadAvg.Load15)))
-		b.WriteString("  ")
+// updateDiskRates derives the throughput of each disk from the previous
+// sample, which is kept until a new one is collected.
+func (m *model) updateDiskRates() {
+	cur := m.stats.diskIO
+	if cur == nil {
+		return
 	}
+	m.diskRates = diskRates(m.prevDisk, cur)
+	m.prevDisk = cur
+}
 
-	b.WriteString(systemInfoStyle.Render(fmt.Sprintf("CPUs: %d", runtime.NumCPU())))
-	b.WriteString("\n")
+// quit saves the current preferences, closes the metrics log and exits.
+// Both are best effort; there's nowhere left to show an error once the
+// program is exiting.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	_ = saveConfig(m.savedPrefs())
+	m.metrics.close()
+	return m, tea.Quit
+}
 
-	// CPU usage
-	if len(m.stats.cpuPercent) > 0 {
-		b.WriteString(systemInfoStyle.Render("CPU: "))
-		for i, usage := range m.stats.cpuPercent {
-			if i > 0 {
-				b.WriteString(" ")
-			}
-			b.WriteString(fmt.Sprintf("%.1f%%", usage))
-			if i >= 7 { // Limit to first 8 cores for display
-				if len(m.stats.cpuPercent) > 8 {
-					b.WriteString(fmt.Sprintf(" (+%d more)", len(m.stats.cpuPercent)-8))
+// requestQuit quits, or with --confirm-quit opens a prompt asking first.
+func (m model) requestQuit() (tea.Model, tea.Cmd) {
+	if m.askQuit {
+		m.quitting = true
+		return m, nil
+	}
+	return m.quit()
+}
+
+// updateQuit captures key presses while the quit prompt is open. Only y
+// quits; ctrl+c always does.
+func (m model) updateQuit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "ctrl+c":
+		return m.quit()
+	}
+	m.quitting = false
+	return m, nil
+}
+
+// prefs returns the preferences kept in the config file as they currently
+// stand.
+func (m model) prefs() options {
+	return options{
+		sortBy:      m.sortBy,
+		thenBy:      m.thenBy,
+		ascending:   m.ascending,
+		interval:    m.interval,
+		maxRows:     m.maxRows,
+		theme:       m.theme,
+		themes:      m.themes,
+		colors:      m.colors,
+		columns:     m.columnList,
+		watch:       m.watch,
+		hidePanels:  m.hidePanels,
+		confirmQuit: m.askQuit,
+	}
+}
+
+// savedPrefs returns the preferences to write to the config file on exit.
+// Those set by a one-off flag keep their value from the file instead, so
+// trying out, say, --watch doesn't make it stick.
+func (m model) savedPrefs() options {
+	opts := m.prefs()
+	if len(m.oneOff) == 0 {
+		return opts
+	}
+	// A missing file means the defaults, which the zero config gives
+	cfg, _ := readConfig()
+	file := cfg.apply(options{})
+	if m.oneOff["watch"] {
+		opts.watch = file.watch
+	}
+	if m.oneOff["columns"] {
+		opts.columns = file.columns
+	}
+	if m.oneOff["hide-panels"] {
+		opts.hidePanels = file.hidePanels
+	}
+	if m.oneOff["confirm-quit"] {
+		opts.confirmQuit = file.confirmQuit
+	}
+	return opts
+}
+
+// updateMouse sorts by a column when its header is clicked, clicking the
+// current sort column again inverts it, and scrolls the table with the
+// wheel.
+func (m model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.confirm != nil || m.picker != nil || m.mode != viewTable {
+		return m, nil
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.table.MoveUp(1)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.table.MoveDown(1)
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		// Column titles sit just below the table's top border
+		if msg.Y != lipgloss.Height(m.renderTop()) {
+			break
+		}
+		key, ok := columnSortKeys[m.columnAt(msg.X)]
+		if !ok {
+			break
+		}
+		if key == m.sortBy {
+			m.ascending = !m.ascending
+			m.updateTable()
+		} else {
+			m.setSort(key)
+		}
+	}
+	return m, nil
+}
+
+// columnAt returns the title of the table column at screen column x, or ""
+// if x is outside every column.
+func (m model) columnAt(x int) string {
+	// Skip the table's left border
+	offset := 1
+	for _, col := range m.table.Columns() {
+		if col.Width <= 0 {
+			continue
+		}
+		// Each cell has one column of padding on either side
+		end := offset + col.Width + 2
+		if x >= offset && x < end {
+			return col.Title
+		}
+		offset = end
+	}
+	return ""
+}
+
+// updateConfirm handles key presses while a kill confirmation is showing.
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		if m.root && !m.confirm.confirmed {
+			m.confirm.confirmed = true
+			return m, nil
+		}
+		req := *m.confirm
+		m.confirm = nil
+		return m, sendSignal(req)
+	case "n", "N", "esc", "q":
+		m.confirm = nil
+	case "ctrl+c":
+		return m.quit()
+	}
+	return m, nil
+}
+
+// updateFilter captures key presses while the filter prompt is open.
+func (m model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEnter:
+		m.filtering = false
+		return m, nil
+	case tea.KeyEsc:
+		m.filtering = false
+		m.setFilter("")
+	case tea.KeyBackspace:
+		if r := []rune(m.filter); len(r) > 0 {
+			m.setFilter(string(r[:len(r)-1]))
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.setFilter(m.filter + string(msg.Runes))
+	default:
+		// Let navigation keys through so the selection can move while typing
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+
+	m.updateTable()
+	return m, nil
+}
+
+// updateSearch captures key presses while the search prompt is open.
+// Unlike the filter, searching keeps every row and only highlights matches.
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEnter:
+		m.searching = false
+		// Land on the first match unless the selection already is one
+		if proc, ok := m.selectedProcess(); !ok || !m.matchesSearch(proc) {
+			m.jumpToMatch(1)
+		}
+	case tea.KeyEsc:
+		m.searching = false
+		m.search = ""
+	case tea.KeyBackspace:
+		if r := []rune(m.search); len(r) > 0 {
+			m.search = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.search += string(msg.Runes)
+	default:
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// jumpToMatch moves the cursor to the next (dir 1) or previous (dir -1)
+// row matching the search, wrapping around the ends of the table.
+func (m *model) jumpToMatch(dir int) {
+	n := len(m.rows)
+	cursor := m.table.Cursor()
+	for step := 1; step <= n; step++ {
+		i := ((cursor+dir*step)%n + n) % n
+		if m.matchesSearch(m.rows[i]) {
+			m.table.SetCursor(i)
+			return
+		}
+	}
+}
+
+// regexPrefix marks a filter as a regular expression matched against the
+// command line rather than a substring.
+const regexPrefix = "~"
+
+// setFilter changes the filter text. A regular expression is compiled as it
+// is typed; while it doesn't compile, filterErr says why and the last valid
+// expression stays in effect.
+func (m *model) setFilter(filter string) {
+	m.filter = filter
+	expr, ok := strings.CutPrefix(filter, regexPrefix)
+	if !ok {
+		m.filterRe, m.filterErr = nil, nil
+		return
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		m.filterErr = err
+		return
+	}
+	m.filterRe, m.filterErr = re, nil
+}
+
+// regexFilter returns the filter text for re, or "" when re is nil.
+func regexFilter(re *regexp.Regexp) string {
+	if re == nil {
+		return ""
+	}
+	return regexPrefix + re.String()
+}
+
+// matchesFilter reports whether the process command, user, cgroup or
+// terminal contains the current filter, ignoring case. A regular
+// expression filter is matched against the command line only.
+func (m model) matchesFilter(proc ProcessInfo) bool {
+	if strings.HasPrefix(m.filter, regexPrefix) {
+		return m.filterRe == nil || m.filterRe.MatchString(m.command(proc))
+	}
+	if m.filter == "" {
+		return true
+	}
+	return containsFold(m.command(proc), m.filter) || containsFold(proc.User, m.filter) ||
+		containsFold(proc.Cgroup, m.filter) || containsFold(proc.TTY, m.filter)
+}
+
+// scaleCPU converts a per-core CPU percentage for display. In Irix mode,
+// the default, it is shown as is and a busy multithreaded process can
+// exceed 100%; in Solaris mode it is divided by the number of CPUs so 100%
+// means the whole machine.
+func (m model) scaleCPU(percent float64) float64 {
+	if !m.solaris {
+		return percent
+	}
+	return min(percent/float64(runtime.NumCPU()), 100)
+}
+
+// matchesUser reports whether proc belongs to the user being shown, if any,
+// and to the user running xtop when only their processes are shown.
+func (m model) matchesUser(proc ProcessInfo) bool {
+	if m.mineOnly && proc.User != m.me {
+		return false
+	}
+	return m.user == "" || proc.User == m.user
+}
+
+// nextUser returns the user after the current one among those running
+// processes, in name order, cycling back to all users after the last.
+func (m model) nextUser() string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, proc := range m.stats.processInfo {
+		if proc.User != "" && !seen[proc.User] {
+			seen[proc.User] = true
+			users = append(users, proc.User)
+		}
+	}
+	sort.Strings(users)
+
+	for _, user := range users {
+		if user > m.user {
+			return user
+		}
+	}
+	return ""
+}
+
+// currentUser returns the name of the user running xtop, shortened like the
+// USER column, or "" if it can't be looked up.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return shortUser(u.Username)
+}
+
+// shortUser cuts a username to maxUserLen bytes.
+func shortUser(name string) string {
+	if len(name) > maxUserLen {
+		return name[:maxUserLen]
+	}
+	return name
+}
+
+// hidden reports whether proc is left out by the idle or kernel thread
+// toggles.
+func (m model) hidden(proc ProcessInfo) bool {
+	if m.hideKernel && isKernelThread(proc) {
+		return true
+	}
+	return m.hideIdle && proc.CPUPerc < idleCPUPercent && proc.MemPerc < idleMemPercent
+}
+
+// matchesSearch reports whether the process command or user contains the
+// current search, ignoring case.
+func (m model) matchesSearch(proc ProcessInfo) bool {
+	if m.search == "" {
+		return false
+	}
+	return containsFold(m.command(proc), m.search) || containsFold(proc.User, m.search)
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// command returns the text for the COMMAND column. With arguments shown,
+// processes without a command line, such as Linux kernel threads, are shown
+// with their name in brackets the way ps does.
+func (m model) command(proc ProcessInfo) string {
+	switch {
+	case !m.showArgs:
+		return proc.Name
+	case proc.Cmdline != "":
+		return proc.Cmdline
+	case strings.HasPrefix(proc.Name, "["):
+		// Already a placeholder label
+		return proc.Name
+	}
+	return "[" + proc.Name + "]"
+}
+
+// decreasedMaxRows returns the row cap one step smaller than the current
+// one. Stepping down from no cap starts from the current process count.
+func (m model) decreasedMaxRows() int {
+	current := m.maxRows
+	if current == 0 {
+		current = len(m.stats.processInfo) / maxRowsStep * maxRowsStep
+		if current == 0 {
+			return maxRowsStep
+		}
+	}
+	if current-maxRowsStep < maxRowsStep {
+		return maxRowsStep
+	}
+	return current - maxRowsStep
+}
+
+// selectedProcess returns the process under the table cursor.
+func (m model) selectedProcess() (ProcessInfo, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.rows) {
+		return ProcessInfo{}, false
+	}
+	return m.rows[cursor], true
+}
+
+// selectedTarget returns the process under the table cursor for an action
+// that only makes sense on one process, such as sending a signal. A row
+// that stands for a whole session or several same-named processes is
+// refused with an error rather than acting on whichever member represents
+// it.
+func (m *model) selectedTarget() (ProcessInfo, bool) {
+	proc, ok := m.selectedProcess()
+	if ok && proc.members > 1 {
+		key := "P"
+		if m.byName {
+			key = "#"
+		}
+		m.err = fmt.Errorf("row stands for %d processes; ungroup with %s to act on one", proc.members, key)
+		return ProcessInfo{}, false
+	}
+	return proc, ok
+}
+
+func sendSignal(req killRequest) tea.Cmd {
+	return func() tea.Msg {
+		p, err := process.NewProcess(req.pid)
+		if err != nil {
+			return actionResultMsg{err: fmt.Errorf("process %d: %w", req.pid, err)}
+		}
+
+		// Terminate and Kill also work where there are no Unix signals
+		switch req.sig {
+		case syscall.SIGTERM:
+			err = p.Terminate()
+		case syscall.SIGKILL:
+			err = p.Kill()
+		default:
+			err = p.SendSignal(req.sig)
+		}
+		if err != nil {
+			return actionResultMsg{err: fmt.Errorf("failed to send %s to %s (%d): %w", req.sigName, req.name, req.pid, err)}
+		}
+		return actionResultMsg{}
+	}
+}
+
+// setCompact switches between the compact view, with only the
+// compactColumns, and the full view with the chosen columns.
+func (m *model) setCompact(compact bool) {
+	m.compact = compact
+	m.layoutColumns()
+}
+
+// baseColumns returns the columns of the current view, before any are
+// scrolled out of sight.
+func (m model) baseColumns() []columnSpec {
+	if m.compact {
+		return columnSpecs(compactColumns)
+	}
+	return m.fullCols
+}
+
+// layoutColumns shows the columns that fit the terminal, scrolled
+// sideways by colOffset, and refills the table.
+func (m *model) layoutColumns() {
+	m.cols, m.colOffset = scrollColumns(m.baseColumns(), m.width, m.colOffset)
+	// Drop the rows first; the table redraws them when its columns change
+	// and they no longer line up
+	m.table.SetRows(nil)
+	m.table.SetColumns(tableColumns(m.width, m.cols))
+	m.updateTable()
+}
+
+// setSort switches to sorting by key. Changing column resets the direction
+// to that column's default; use the invert key to flip it.
+func (m *model) setSort(key string) {
+	if key != m.sortBy {
+		m.sortBy = key
+		m.ascending = defaultAscending(key)
+	}
+	m.updateTable()
+}
+
+// compareProcs orders a and b by the sort column key, ascending. It
+// returns 0 for an unknown key.
+func compareProcs(key string, a, b ProcessInfo, now time.Time) int {
+	switch key {
+	case "cpu":
+		return cmp.Compare(a.CPUPerc, b.CPUPerc)
+	case "cpuavg":
+		return cmp.Compare(a.CPUAvg, b.CPUAvg)
+	case "memory":
+		return cmp.Compare(a.MemPerc, b.MemPerc)
+	case "memrss":
+		return cmp.Compare(a.MemRSS, b.MemRSS)
+	case "swap":
+		return cmp.Compare(a.Swap, b.Swap)
+	case "threads":
+		return cmp.Compare(a.Threads, b.Threads)
+	case "fds":
+		return cmp.Compare(a.NumFDs, b.NumFDs)
+	case "conns":
+		return cmp.Compare(a.Conns, b.Conns)
+	case "diskread":
+		return cmp.Compare(a.DiskReadBps, b.DiskReadBps)
+	case "diskwrite":
+		return cmp.Compare(a.DiskWriteBps, b.DiskWriteBps)
+	case "uptime":
+		return cmp.Compare(a.uptime(now), b.uptime(now))
+	case "time":
+		return cmp.Compare(a.cpuTime, b.cpuTime)
+	case "pid":
+		return cmp.Compare(a.PID, b.PID)
+	case "name":
+		return cmp.Compare(a.Name, b.Name)
+	}
+	return 0
+}
+
+// defaultAscending returns the natural direction for a sort column: names
+// and PIDs read top to bottom, while usage figures put the largest first.
+func defaultAscending(key string) bool {
+	return key == "name" || key == "pid"
+}
+
+// sortKeys are the columns updateTable can sort by.
+var sortKeys = []string{"cpu", "cpuavg", "memory", "memrss", "swap", "threads", "fds", "conns", "diskread", "diskwrite", "uptime", "time", "pid", "name"}
+
+// sortAliases lets --sort and --then-by also take the --columns names of
+// the columns whose sort key differs.
+var sortAliases = map[string]string{
+	"mem":     "memory",
+	"res":     "memrss",
+	"command": "name",
+}
+
+// isSortKey reports whether key is one of the columns updateTable can sort by.
+func isSortKey(key string) bool {
+	return slices.Contains(sortKeys, key)
+}
+
+// parseSortKey resolves a sort column given on the command line.
+func parseSortKey(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if alias, ok := sortAliases[key]; ok {
+		key = alias
+	}
+	if !isSortKey(key) {
+		return "", fmt.Errorf("unknown sort column %q (valid columns: %s)", key, strings.Join(sortKeys, ", "))
+	}
+	return key, nil
+}
+
+// renice changes the niceness of proc by delta, clamped to the valid
+// -20..19 range.
+func renice(proc ProcessInfo, delta int) tea.Cmd {
+	return func() tea.Msg {
+		nice := int(proc.Nice) + delta
+		if nice < -20 {
+			nice = -20
+		} else if nice > 19 {
+			nice = 19
+		}
+
+		if err := setNice(proc.PID, nice); err != nil {
+			return actionResultMsg{err: fmt.Errorf("failed to renice %s (%d) to %d: %w", proc.Name, proc.PID, nice, err)}
+		}
+		return actionResultMsg{}
+	}
+}
+
+func (m *model) updateTable() {
+	now := time.Now()
+
+	// Sessions and commands are made up of whole processes, so their
+	// threads are folded in first
+	procs := m.stats.processInfo
+	if m.grouped || m.sessions || m.byName {
+		procs = groupThreads(procs)
+	}
+	if m.sessions {
+		procs = groupSessions(procs)
+	}
+	if m.byName {
+		procs = groupNames(procs)
+	}
+	if m.solaris {
+		// Copy so the scaling isn't applied to the sample more than once
+		scaled := make([]ProcessInfo, len(procs))
+		for i, proc := range procs {
+			proc.CPUPerc = m.scaleCPU(proc.CPUPerc)
+			proc.CPUAvg = m.scaleCPU(proc.CPUAvg)
+			scaled[i] = proc
+		}
+		procs = scaled
+	}
+	m.total = len(procs)
+
+	// Sort processes. Ties on the sort column fall back to the tiebreak
+	// column in its natural direction, then to PID, so equal rows keep a
+	// steady order between refreshes instead of swapping places.
+	sort.SliceStable(procs, func(i, j int) bool {
+		c := compareProcs(m.sortBy, procs[i], procs[j], now)
+		if !m.ascending {
+			c = -c
+		}
+		if c == 0 && m.thenBy != m.sortBy {
+			c = compareProcs(m.thenBy, procs[i], procs[j], now)
+			if !defaultAscending(m.thenBy) {
+				c = -c
+			}
+		}
+		if c == 0 {
+			c = cmp.Compare(procs[i].PID, procs[j].PID)
+		}
+		return c < 0
+	})
+
+	// With --sort-interval, refreshes in between sorts only update the
+	// figures and keep each row in place. New processes go after the
+	// rest, in sorted order among themselves.
+	if m.holdSort && m.sortRank != nil {
+		rank := func(pid int32) int {
+			if r, ok := m.sortRank[pid]; ok {
+				return r
+			}
+			return len(m.sortRank)
+		}
+		sort.SliceStable(procs, func(i, j int) bool {
+			return rank(procs[i].PID) < rank(procs[j].PID)
+		})
+	} else {
+		m.sortRank = make(map[int32]int, len(procs))
+		for i, proc := range procs {
+			m.sortRank[proc.PID] = i
+		}
+		m.sortedAt = now
+	}
+
+	scope := m.pidScope()
+	var visible []ProcessInfo
+	for _, proc := range procs {
+		if scope != nil && !scope[proc.PID] {
+			continue
+		}
+		if m.matchesFilter(proc) && m.matchesUser(proc) && !m.hidden(proc) {
+			visible = append(visible, proc)
+		}
+	}
+	m.visible = len(visible)
+
+	// In tree mode the sort order above only applies within sibling groups
+	entries := make([]treeEntry, 0, len(visible))
+	if m.treeView {
+		entries = buildTree(visible)
+	} else {
+		for _, proc := range visible {
+			entries = append(entries, treeEntry{proc: proc})
+		}
+	}
+
+	// Split into pages, turning to the followed process's page so it stays
+	// in view
+	m.pages = 1
+	if per := m.pageRows(); per > 0 && len(entries) > per {
+		m.pages = (len(entries) + per - 1) / per
+		if m.follow != 0 {
+			for i, entry := range entries {
+				if entry.proc.PID == m.follow {
+					m.page = i / per
+					break
 				}
-				break
 			}
 		}
+		m.page = min(m.page, m.pages-1)
+		entries = entries[m.page*per : min((m.page+1)*per, len(entries))]
+	} else {
+		m.page = 0
+	}
+
+	// Convert to table rows
+	var rows []table.Row
+	m.rows = m.rows[:0]
+	for _, entry := range entries {
+		rows = append(rows, m.row(entry, now))
+		m.rows = append(m.rows, entry.proc)
+	}
+
+	// Keep the selection where it was. The table only clamps the cursor
+	// when rows are removed, and leaves it at -1 once the table has been
+	// empty, which would hide the selection when rows come back.
+	cursor := m.table.Cursor()
+	m.table.SetRows(rows)
+	m.table.SetCursor(max(cursor, 0))
+
+	if m.follow != 0 {
+		m.moveToFollowed()
+	}
+}
+
+// pageRows returns how many processes go on a page: as many as the table
+// has room for, capped at maxRows. 0 puts them all on one page, which only
+// happens before the terminal size is known and without a cap.
+func (m model) pageRows() int {
+	rows := m.maxRows
+	if m.height > 0 && (rows == 0 || m.table.Height() < rows) {
+		rows = max(m.table.Height(), 1)
+	}
+	return rows
+}
+
+// flipPage turns to the next or previous page, wrapping around, with the
+// cursor on the row nearest the page it came from.
+func (m *model) flipPage(next bool) {
+	if next {
+		m.page = (m.page + 1) % m.pages
+		m.table.SetCursor(0)
+	} else {
+		m.page = (m.page + m.pages - 1) % m.pages
+		m.table.SetCursor(m.pageRows() - 1)
+	}
+	m.updateTable()
+}
+
+// halfPage moves the cursor half a page onto the next or previous page
+// when that's further than the current page goes. It reports false when
+// the move stays on this page, which the table handles itself.
+func (m *model) halfPage(down bool) bool {
+	per := m.pageRows()
+	step := max(per/2, 1)
+	cursor := m.table.Cursor()
+	switch {
+	case down && cursor+step >= len(m.rows) && m.page < m.pages-1:
+		m.page++
+		m.updateTable()
+		m.table.SetCursor(cursor + step - per)
+	case !down && cursor-step < 0 && m.page > 0:
+		m.page--
+		m.updateTable()
+		m.table.SetCursor(cursor - step + per)
+	default:
+		return false
+	}
+	return true
+}
+
+// moveToFollowed puts the cursor on the followed process. A process that
+// is only filtered out or beyond the row limit is still followed and
+// leaves the cursor where it is; one that has exited stops the follow.
+func (m *model) moveToFollowed() {
+	for i, proc := range m.rows {
+		if proc.PID == m.follow {
+			m.table.SetCursor(i)
+			return
+		}
+	}
+	for _, proc := range m.stats.processInfo {
+		if proc.PID == m.follow {
+			return
+		}
+	}
+	m.notice = fmt.Sprintf("Process %d exited, no longer following", m.follow)
+	m.follow = 0
+}
+
+func (m model) View() string {
+	var b strings.Builder
+
+	b.WriteString(m.renderTop())
+
+	footer := m.renderFooter()
+
+	// The quit prompt, kill confirmation and the signal menu replace the
+	// table until answered
+	if m.quitting {
+		b.WriteString(m.styles.confirm.Render("Quit xtop?\n\n[y] Yes   [n] No"))
+		b.WriteString("\n\n")
+	} else if m.confirm != nil {
+		prompt := fmt.Sprintf("Send %s to %s (PID %d)?\n\n[y] Yes   [n] No",
+			m.confirm.sigName, m.confirm.name, m.confirm.pid)
+		if m.confirm.confirmed {
+			prompt = fmt.Sprintf("You are root, so this can't be refused.\nReally send %s to %s (PID %d)?\n\n[y] Yes, send it   [n] No",
+				m.confirm.sigName, m.confirm.name, m.confirm.pid)
+		}
+		b.WriteString(m.styles.confirm.Render(prompt))
+		b.WriteString("\n\n")
+	} else if m.picker != nil {
+		b.WriteString(m.renderSignalPicker())
+		b.WriteString("\n\n")
+	} else if m.mode == viewDetail {
+		b.WriteString(m.renderDetail())
+		b.WriteString("\n")
+	} else if m.mode == viewDiff {
+		b.WriteString(m.renderDiff())
+		b.WriteString("\n")
+	} else if m.mode == viewPorts {
+		// Sized by fitTable, like the process table
+		b.WriteString(m.renderPorts())
+		b.WriteString("\n")
+	} else {
+		// Process table, sized by fitTable
+		b.WriteString(m.styles.processTable.Render(m.colorizeTable(m.table.View())))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(footer)
+
+	return b.String()
+}
+
+// renderFooter renders everything below the process table: errors and
+// notices, xtop's own usage and the key help.
+func (m model) renderFooter() string {
+	var footer strings.Builder
+
+	// Subsystems that failed on the last refresh
+	for _, name := range m.stats.failedSubsystems() {
+		if m.compact {
+			break
+		}
+		footer.WriteString(m.styles.error.Render(fmt.Sprintf("%s unavailable: %v", name, m.stats.errs[name])))
+		footer.WriteString("\n")
+	}
+
+	if m.err != nil {
+		footer.WriteString(m.styles.error.Render(fmt.Sprintf("Error: %v", m.err)))
+		footer.WriteString("\n")
+	} else if m.notice != "" {
+		footer.WriteString(m.styles.systemInfo.Render(m.notice))
+		footer.WriteString("\n")
+	}
+
+	// Help, wrapped to the terminal width so its height is known
+	helpStyle := lipgloss.NewStyle().Faint(true)
+	if m.width > 0 {
+		helpStyle = helpStyle.Width(m.width)
+	}
+
+	// The compact view leaves out everything but the table
+	if !m.compact {
+		// xtop's own footprint
+		if usage := m.selfUsage(); usage != "" {
+			footer.WriteString(helpStyle.Render(usage))
+			footer.WriteString("\n")
+		}
+
+		help := "Controls: [c] CPU sort • [m] Memory sort • [r] RES sort • [T] Threads sort • [f] FD sort • [C] Connections sort • [R/W] Disk read/write sort • [s] Uptime sort • [p] PID sort • [n] Name sort • [i] Invert sort • [o] Re-sort now • [a] Args • [u] Cycle user • [U] My processes • [t] Tree • [A] Group threads • [P] Group sessions • [#] Merge commands • [S] Irix/Solaris CPU% (htop's I) • [I] Hide idle • [K] Hide kthreads • [F] Follow process • [h] History range • [M] Memory breakdown • [1] Per-core CPU • [2-5] Uptime/Load/CPU/Memory panels • [E] TIME+ column • [V] AVG% column • [v] Compact view • [←/→] Scroll columns • [D] Per-disk I/O • [/] Filter (~regexp) • [?] Search • [space] Pause • [F5] Refresh now • [e] Export • [b/d] Baseline/Diff • [L] Listening ports • [y/Y] Copy PID/command • [x/X] Term/Kill • [z] Signal menu • [</>] Nice -/+ • [+/-] Interval • [[/]] Rows • [q] Quit"
+		nav := "Navigate: [↑/k] Up • [↓/j] Down • [pgup/pgdn] Page • [ctrl+u/ctrl+d] Half page • [g/home] Top • [G/end] Bottom • [enter] Details"
+		switch m.mode {
+		case viewDetail:
+			footer.WriteString(helpStyle.Render("Controls: [esc/enter] Back to table • [↑/↓] Scroll environment • [r] Reveal/hide secrets • [a] Set CPU affinity • [e] Dump as JSON • [q] Quit"))
+		case viewDiff:
+			footer.WriteString(helpStyle.Render("Controls: [esc/d] Back to table • [b] New baseline • [q] Quit"))
+		case viewPorts:
+			footer.WriteString(helpStyle.Render("Controls: [esc/L] Back to table • [↑/↓] Scroll • [q] Quit"))
+		default:
+			footer.WriteString(helpStyle.Render(statusLegend))
+			footer.WriteString("\n")
+			footer.WriteString(helpStyle.Render(nav))
+			footer.WriteString("\n")
+			footer.WriteString(helpStyle.Render(help))
+		}
+	}
+	return footer.String()
+}
+
+// renderTop renders everything above the process table: the title, the
+// system summary and the sort/filter status line.
+func (m model) renderTop() string {
+	if m.compact {
+		return m.renderMinimalTop()
+	}
+
+	var b strings.Builder
+
+	// Header
+	// While an alert is raised the title flashes between the alert and
+	// normal styles on each refresh
+	headerStyle := m.styles.header
+	if m.alerts.flash {
+		headerStyle = m.styles.alert
+	}
+	header := headerStyle.Render("GoTop - System Monitor")
+	b.WriteString(header)
+	if m.alerts.active() {
+		b.WriteString(" " + m.styles.error.Render("ALERT: "+m.alerts.message(m.stats)))
+	}
+	if m.paused {
+		b.WriteString(" " + m.styles.error.Render("PAUSED"))
+	}
+	if m.root {
+		b.WriteString(" " + m.styles.cpuMid.Render("ROOT"))
+	}
+	// Zombies mean some parent isn't reaping its children, so they're
+	// flagged next to the title where they can't be missed
+	if zombies := countTasks(m.stats.processInfo).zombie; zombies > 0 {
+		noun := "zombies"
+		if zombies == 1 {
+			noun = "zombie"
+		}
+		b.WriteString(" " + m.styles.error.Render(fmt.Sprintf("⚠ %d %s", zombies, noun)))
+	}
+	// A process stuck in uninterruptible sleep usually means a hung mount
+	// or failing disk. Brief waits are normal, so only those that last
+	// are flagged.
+	if stuck := m.stuckProcesses(); stuck > 0 {
+		noun := "processes"
+		if stuck == 1 {
+			noun = "process"
+		}
+		b.WriteString(" " + m.styles.cpuMid.Bold(true).Render(fmt.Sprintf("⚠ %d %s in uninterruptible sleep", stuck, noun)))
+	}
+	b.WriteString("\n\n")
+
+	// System info, collapsed to a single line on very narrow terminals
+	if m.width > 0 && m.width < compactWidth {
+		b.WriteString(m.renderCompactSystemInfo())
+	} else {
+		b.WriteString(m.renderSystemInfo())
+	}
+	b.WriteString("\n")
+
+	// Sort indicator
+	sortIndicator := fmt.Sprintf("Sorted by: %s (%s), then %s", m.sortBy,
+		map[bool]string{true: "ascending", false: "descending"}[m.ascending], m.thenBy)
+	b.WriteString(sortIndicator)
+	if m.sortEvery > 0 {
+		b.WriteString(fmt.Sprintf(" every %s", m.sortEvery))
+	}
+	if m.treeView {
+		b.WriteString("  [tree]")
+	}
+	if m.grouped {
+		b.WriteString("  [threads grouped]")
+	}
+	if m.sessions {
+		b.WriteString("  [sessions grouped]")
+	}
+	if m.byName {
+		b.WriteString("  [commands merged]")
+	}
+	if m.follow != 0 {
+		b.WriteString(fmt.Sprintf("  [following %d]", m.follow))
+	}
+	if !m.systemLineShown() && (m.width == 0 || m.width >= compactWidth) {
+		b.WriteString(fmt.Sprintf("  Refresh: %s", m.interval))
+	}
+	if m.solaris {
+		b.WriteString("  CPU%: solaris (of all CPUs)")
+	} else {
+		b.WriteString("  CPU%: irix (per core)")
+	}
+	if m.hideIdle {
+		b.WriteString("  [idle hidden]")
+	}
+	if m.hideKernel {
+		b.WriteString("  [kthreads hidden]")
+	}
+	if m.user != "" {
+		b.WriteString(fmt.Sprintf("  User: %s", m.user))
+	}
+	if m.mineOnly {
+		b.WriteString(fmt.Sprintf("  [only %s's]", m.me))
+	}
+	if len(m.pids) > 0 {
+		pids := make([]string, len(m.pids))
+		for i, pid := range m.pids {
+			pids[i] = strconv.Itoa(int(pid))
+		}
+		b.WriteString("  PIDs: " + strings.Join(pids, ","))
+		if m.pidKids {
+			b.WriteString(" and children")
+		}
+		if missing := m.missingPIDs(); len(missing) > 0 {
+			b.WriteString(" " + m.styles.error.Render("(not running: "+strings.Join(missing, ",")+")"))
+		}
+	}
+	if m.hideIdle || m.hideKernel || m.filter != "" || m.user != "" || m.mineOnly || len(m.pids) > 0 {
+		b.WriteString(fmt.Sprintf("  Showing: %d/%d", m.visible, m.total))
+	}
+	if hidden := len(m.baseColumns()) - len(m.cols); hidden > 0 {
+		b.WriteString(fmt.Sprintf("  [%d columns off screen, ←/→]", hidden))
+	}
+	if m.maxRows > 0 {
+		b.WriteString(fmt.Sprintf("  Rows: %d", m.maxRows))
+	} else {
+		b.WriteString("  Rows: fit")
+	}
+	if m.pages > 1 {
+		b.WriteString(fmt.Sprintf("  Page %d/%d", m.page+1, m.pages))
+	}
+	if m.metrics != nil {
+		b.WriteString("  [logging]")
+	} else if m.logErr != nil {
+		b.WriteString("  " + m.styles.error.Render(fmt.Sprintf("Log disabled: %v", m.logErr)))
+	}
+	if m.filtering {
+		b.WriteString(fmt.Sprintf("  Filter: %s█", m.filter))
+	} else if m.filter != "" {
+		b.WriteString(fmt.Sprintf("  Filter: %s [esc to clear]", m.filter))
+	}
+	if m.filterErr != nil {
+		b.WriteString("  " + m.styles.error.Render(fmt.Sprintf("Bad regexp: %v", m.filterErr)))
+	}
+	if m.searching {
+		b.WriteString(fmt.Sprintf("  Search: %s█", m.search))
+	} else if m.search != "" {
+		b.WriteString(fmt.Sprintf("  Search: %s [n/N next/prev, esc to clear]", m.search))
+	}
+	b.WriteString("\n\n")
+
+	return b.String()
+}
+
+// selfUsage describes xtop's own CPU and memory use, from its entry in the
+// process list, so it is easy to check that the monitor itself stays light.
+func (m model) selfUsage() string {
+	if m.stats.heapAlloc == 0 {
+		return ""
+	}
+	pid := int32(os.Getpid())
+	for _, proc := range m.stats.processInfo {
+		if proc.PID == pid {
+			return fmt.Sprintf("xtop: %.1f%% CPU, %s RES, %s heap",
+				m.scaleCPU(proc.CPUPerc), formatBytes(proc.MemRSS), formatBytes(m.stats.heapAlloc))
+		}
+	}
+	return "xtop: " + formatBytes(m.stats.heapAlloc) + " heap"
+}
+
+// updateBlocked counts how many refreshes in a row each process has been
+// in uninterruptible sleep.
+func (m *model) updateBlocked() {
+	blocked := make(map[int32]int)
+	for _, proc := range m.stats.processInfo {
+		if statusCategory(proc.Status) == "blocked" {
+			blocked[proc.PID] = m.blocked[proc.PID] + 1
+		}
+	}
+	m.blocked = blocked
+}
+
+// stuckProcesses returns how many processes have been in uninterruptible
+// sleep for at least stuckTicks refreshes.
+func (m model) stuckProcesses() int {
+	var stuck int
+	for _, ticks := range m.blocked {
+		if ticks >= stuckTicks {
+			stuck++
+		}
+	}
+	return stuck
+}
+
+// label returns full, or short when the terminal is too narrow for it.
+func (m model) label(full, short string) string {
+	if m.width > 0 && m.width < narrowWidth {
+		return short
+	}
+	return full
+}
+
+// systemLineShown reports whether the line with uptime, load and the CPU
+// count has anything on it besides the refresh interval.
+func (m model) systemLineShown() bool {
+	return m.stats.uptime > 0 && m.showPanel("uptime") ||
+		m.stats.loadAvg != nil && m.showPanel("load") ||
+		m.showPanel("cpu")
+}
+
+// renderSystemInfo renders the system summary shown above the process
+// table.
+func (m model) renderSystemInfo() string {
+	var b strings.Builder
+
+	// The refresh interval goes with the panels on this line, and moves
+	// to the status line when they are all hidden
+	if m.systemLineShown() {
+		if m.stats.uptime > 0 && m.showPanel("uptime") {
+			uptime := formatDuration(m.stats.uptime)
+			b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s: %s", m.label("Uptime", "Up"), uptime)))
+			b.WriteString("  ")
+		}
+
+		if m.stats.loadAvg != nil && m.showPanel("load") {
+			b.WriteString(m.styles.systemInfo.Render(m.label("Load", "Ld") + ": "))
+			b.WriteString(m.styles.loadAvg(m.stats.loadAvg.Load1, runtime.NumCPU()).Render(fmt.Sprintf("%.2f %.2f %.2f",
+				m.stats.loadAvg.Load1, m.stats.loadAvg.Load5, m.stats.loadAvg.Load15)))
+			b.WriteString("  ")
+		}
+
+		if m.showPanel("cpu") {
+			b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("CPUs: %d", runtime.NumCPU())))
+			b.WriteString("  ")
+		}
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s: %s", m.label("Refresh", "Int"), m.interval)))
+		b.WriteString("\n")
+	}
+
+	// Task summary
+	if len(m.stats.processInfo) > 0 {
+		tasks := countTasks(m.stats.processInfo)
+		format := "Tasks: %d total, %d threads, %d running, %d sleeping, %d stopped, %d zombie"
+		if m.width > 0 && m.width < narrowWidth {
+			format = "Tasks: %d, %d thr, %d run, %d slp, %d stop, %d zomb"
+		}
+		line := fmt.Sprintf(format,
+			tasks.total, tasks.threads, tasks.running, tasks.sleeping, tasks.stopped, tasks.zombie)
+		if tasks.blocked > 0 {
+			line += fmt.Sprintf(", %d disk wait", tasks.blocked)
+		}
+		if m.newProcs > 0 {
+			line += fmt.Sprintf(", new: %d", m.newProcs)
+		}
+		b.WriteString(m.styles.systemInfo.Render(line))
+		b.WriteString("\n")
+		b.WriteString(m.styles.systemInfo.Render("States: "))
+		b.WriteString(m.renderStateBar(tasks))
+		b.WriteString("\n")
+	}
+
+	// CPU usage for the whole machine, then one bar per core wrapped to
+	// the terminal width
+	if len(m.stats.cpuPercent) > 0 && m.showPanel("cpu") {
+		b.WriteString(m.styles.systemInfo.Render("CPU: "))
+		b.WriteString(m.renderCPUBar(m.stats.cpuTotal, totalBarWidth))
+		b.WriteString(fmt.Sprintf(" %5.1f%%", m.stats.cpuTotal))
+		b.WriteString("\n")
+		if !m.hideCores {
+			b.WriteString(m.renderCPUGrid())
+		}
+
+		samples := m.cpuHistory.last(m.histRange)
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s (%d): ", m.label("CPU history", "Hist"), m.histRange)))
+		b.WriteString(m.styles.load(m.stats.cpuTotal).Render(sparkline(samples, 100)))
+		b.WriteString(fmt.Sprintf(" %.1f%%", m.stats.cpuTotal))
+		b.WriteString("\n")
+	}
+
+	// 1-minute load, scaled so a full block is one runnable task per CPU
+	// unless the load has gone higher than that
+	if m.stats.loadAvg != nil && m.showPanel("load") {
+		samples := m.loadHist.last(m.histRange)
+		peak := float64(runtime.NumCPU())
+		for _, v := range samples {
+			peak = max(peak, v)
+		}
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s (%d): ", m.label("Load history", "LdHist"), m.histRange)))
+		b.WriteString(m.styles.loadAvg(m.stats.loadAvg.Load1, runtime.NumCPU()).Render(sparkline(samples, peak)))
+		b.WriteString(fmt.Sprintf(" %.2f", m.stats.loadAvg.Load1))
+		b.WriteString("\n")
+	}
+
+	// Heaviest CPU users, regardless of how the table is sorted or scrolled
+	if len(m.topCPU) > 0 {
+		b.WriteString(m.renderTopConsumers())
+		b.WriteString("\n")
+	}
+
+	// CPU temperature
+	if temp, ok := cpuTemperature(m.stats.temps); ok {
+		style := m.styles.systemInfo
+		switch {
+		case temp > 85:
+			style = m.styles.error
+		case temp > 70:
+			style = m.styles.cpuMid.Bold(true)
+		}
+		b.WriteString(style.Render(fmt.Sprintf("Temp: %.0f°C", temp)))
+		b.WriteString("\n")
+	}
+
+	// Battery
+	if batt := m.stats.battery; batt != nil {
+		style := m.styles.systemInfo
+		if batt.percent < lowBatteryPercent {
+			style = m.styles.error
+		}
+		b.WriteString(style.Render(fmt.Sprintf("Batt: %.0f%% (%s)", batt.percent, batt.status)))
+		b.WriteString("\n")
+	}
+
+	// Memory and swap usage
+	memStats, swapStats := m.stats.memStats, m.stats.swapStats
+	if !m.showPanel("memory") {
+		memStats, swapStats = nil, nil
+	}
+	if memStats != nil {
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s: %s/%s (%.1f%%)",
+			m.label("Memory", "Mem"), m.formatMem(memStats.Used), m.formatMem(memStats.Total),
+			memStats.UsedPercent)))
+		b.WriteString("  ")
+	}
+
+	if swapStats != nil {
+		if swapStats.Total == 0 {
+			b.WriteString(m.styles.systemInfo.Render("Swap: none"))
+		} else {
+			b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("Swap: %s/%s (%.0f%%)",
+				m.formatMem(swapStats.Used), m.formatMem(swapStats.Total),
+				swapStats.UsedPercent)))
+		}
+	}
+	if memStats != nil || swapStats != nil {
+		b.WriteString("\n")
+	}
+
+	// Memory breakdown, separating reclaimable cache from real pressure.
+	// Buffers and cache are only reported on some platforms.
+	if m.memDetail && memStats != nil {
+		vm := memStats
+		parts := []string{m.formatMem(vm.Used) + " used"}
+		if vm.Buffers > 0 {
+			parts = append(parts, m.formatMem(vm.Buffers)+" buff")
+		}
+		if vm.Cached > 0 {
+			parts = append(parts, m.formatMem(vm.Cached)+" cache")
+		}
+		parts = append(parts, m.formatMem(vm.Available)+" avail")
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s: %s",
+			m.label("Memory", "Mem"), strings.Join(parts, ", "))))
+		b.WriteString("\n")
+	}
+
+	// Network throughput
+	if m.stats.netIO != nil {
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("Net: ↓%s ↑%s",
+			formatRate(m.netRecvBps), formatRate(m.netSentBps))))
+		b.WriteString("\n")
+	}
+
+	// Disk throughput summed over all disks, then per disk when toggled
+	if m.stats.diskIO != nil {
+		var read, write float64
+		for _, rate := range m.diskRates {
+			read += rate.read
+			write += rate.write
+		}
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("Disk I/O: ↓%s ↑%s",
+			formatRate(read), formatRate(write))))
+		b.WriteString("\n")
+		if m.perDisk {
+			for _, rate := range m.diskRates {
+				b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("  %s: ↓%s ↑%s",
+					rate.name, formatRate(rate.read), formatRate(rate.write))))
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	// GPU usage, one line per card
+	for _, gpu := range m.stats.gpus {
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("GPU%d: %.0f%% %.1fG/%.1fG",
+			gpu.index, gpu.util,
+			float64(gpu.memUsed)/(1024*1024*1024), float64(gpu.memTotal)/(1024*1024*1024))))
+		b.WriteString("\n")
+	}
+
+	// Pressure stall information, highlighted when tasks are kept waiting
+	if len(m.stats.psi) > 0 {
+		b.WriteString(m.renderPSI())
+		b.WriteString("\n")
+	}
+
+	// Disk usage, one line per mountpoint
+	for _, usage := range m.stats.diskUsage {
+		label := "Disk"
+		if len(m.stats.diskUsage) > 1 {
+			label = "Disk " + usage.Path
+		}
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("%s: %s/%s (%.0f%%)",
+			label, formatBytes(usage.Used), formatBytes(usage.Total), usage.UsedPercent)))
 		b.WriteString("\n")
 	}
+	return b.String()
+}
+
+// renderCompactSystemInfo renders the most important system figures on a
+// single line for terminals too narrow for the full summary.
+func (m model) renderCompactSystemInfo() string {
+	var parts []string
+	if m.stats.uptime > 0 && m.showPanel("uptime") {
+		parts = append(parts, "up "+formatDuration(m.stats.uptime))
+	}
+	if m.stats.loadAvg != nil && m.showPanel("load") {
+		parts = append(parts, fmt.Sprintf("ld %.2f", m.stats.loadAvg.Load1))
+	}
+	if len(m.stats.cpuPercent) > 0 && m.showPanel("cpu") {
+		parts = append(parts, fmt.Sprintf("cpu %.0f%%", m.stats.cpuTotal))
+	}
+	if m.stats.memStats != nil && m.showPanel("memory") {
+		parts = append(parts, fmt.Sprintf("mem %.0f%%", m.stats.memStats.UsedPercent))
+	}
+	return m.styles.systemInfo.Render(strings.Join(parts, " · ")) + "\n"
+}
+
+// topConsumers returns up to n processes using the most CPU, leaving out
+// those that are idle.
+func topConsumers(procs []ProcessInfo, n int) []ProcessInfo {
+	var busy []ProcessInfo
+	for _, proc := range procs {
+		if proc.CPUPerc > 0 {
+			busy = append(busy, proc)
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].CPUPerc > busy[j].CPUPerc
+	})
+	if len(busy) > n {
+		busy = busy[:n]
+	}
+	return busy
+}
 
-	// Memory usage
+// renderMinimalTop is the one-line header of the compact view: overall CPU
+// and memory use, anything that needs attention, and the filter or search
+// being typed.
+func (m model) renderMinimalTop() string {
+	var parts []string
+	if len(m.stats.cpuPercent) > 0 {
+		parts = append(parts, m.styles.load(m.stats.cpuTotal).Render(fmt.Sprintf("CPU %.0f%%", m.stats.cpuTotal)))
+	}
 	if m.stats.memStats != nil {
-		memUsed := float64(m.stats.memStats.Used) / (1024 * 1024 * 1024)
-		memTotal := float64(m.stats.memStats.Total) / (1024 * 1024 * 1024)
-		b.WriteString(systemInfoStyle.Render(fmt.Sprintf("Memory: %.1fG/%.1fG (%.1f%%)", 
-			memUsed, memTotal, m.stats.memStats.UsedPercent)))
-		b.WriteString("\n\n")
+		parts = append(parts, m.styles.load(m.stats.memStats.UsedPercent).Render(fmt.Sprintf("Mem %.0f%%", m.stats.memStats.UsedPercent)))
+	}
+	if m.alerts.active() {
+		parts = append(parts, m.styles.error.Render("ALERT"))
 	}
+	if m.paused {
+		parts = append(parts, m.styles.error.Render("PAUSED"))
+	}
+	if m.root {
+		parts = append(parts, m.styles.cpuMid.Render("ROOT"))
+	}
+	if m.filtering {
+		parts = append(parts, fmt.Sprintf("Filter: %s█", m.filter))
+	} else if m.filter != "" {
+		parts = append(parts, "Filter: "+m.filter)
+	}
+	if m.searching {
+		parts = append(parts, fmt.Sprintf("Search: %s█", m.search))
+	} else if m.search != "" {
+		parts = append(parts, "Search: "+m.search)
+	}
+	parts = append(parts, lipgloss.NewStyle().Faint(true).Render("[v] Full view"))
 
-	// Sort indicator
-	sortIndicator := fmt.Sprintf("Sorted by: %s (%s)", m.sortBy, 
-		map[bool]string{true: "ascending", false: "descending"}[m.ascending])
-	b.WriteString(sortIndicator + "\n\n")
+	style := lipgloss.NewStyle()
+	if m.width > 0 {
+		style = style.MaxWidth(m.width)
+	}
+	return style.Render(strings.Join(parts, "  ")) + "\n"
+}
 
-	// Process table
-	b.WriteString(processTableStyle.Render(m.table.View()))
-	b.WriteString("\n\n")
+// renderTopConsumers renders a line such as "Top: chrome(42%) java(31%)",
+// cut to the terminal width.
+func (m model) renderTopConsumers() string {
+	parts := make([]string, 0, len(m.topCPU))
+	for _, proc := range m.topCPU {
+		name := []rune(proc.Name)
+		if len(name) > topNameLen {
+			name = append(name[:topNameLen-1], '…')
+		}
+		parts = append(parts, fmt.Sprintf("%s(%.0f%%)", string(name), m.scaleCPU(proc.CPUPerc)))
+	}
+
+	style := m.styles.systemInfo
+	if m.width > 0 {
+		style = style.MaxWidth(m.width)
+	}
+	return style.Render("Top: " + strings.Join(parts, " "))
+}
+
+// averageCPU returns the mean utilization across all cores.
+func averageCPU(perCore []float64) float64 {
+	var sum float64
+	for _, usage := range perCore {
+		sum += usage
+	}
+	return sum / float64(len(perCore))
+}
 
-	// Help
-	help := "Controls: [c] CPU sort • [m] Memory sort • [p] PID sort • [n] Name sort • [q] Quit"
-	b.WriteString(lipgloss.NewStyle().Faint(true).Render(help))
+// nextHistoryRange returns the sparkline length that follows current,
+// wrapping back to the shortest.
+func nextHistoryRange(current int) int {
+	for i, r := range historyRanges {
+		if r == current {
+			return historyRanges[(i+1)%len(historyRanges)]
+		}
+	}
+	return historyRanges[0]
+}
+
+// renderCPUGrid lays out a bar for every core in as many columns as fit
+// in the current terminal width. On machines with more than one socket the
+// cores are grouped under a heading per socket, with the socket's average.
+func (m model) renderCPUGrid() string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	perLine := width / cpuCellWidth
+	if perLine < 1 {
+		perLine = 1
+	}
+
+	usage := m.stats.cpuPercent
+	if len(m.sockets) != len(usage) {
+		all := make([]int, len(usage))
+		for i := range all {
+			all[i] = i
+		}
+		return m.renderCPUCells(all, perLine)
+	}
+
+	ids := slices.Clone(m.sockets)
+	slices.Sort(ids)
+	var b strings.Builder
+	for _, id := range slices.Compact(ids) {
+		var cores []int
+		var loads []float64
+		for i, socket := range m.sockets {
+			if socket == id {
+				cores = append(cores, i)
+				loads = append(loads, usage[i])
+			}
+		}
+		avg := averageCPU(loads)
+		b.WriteString(m.styles.systemInfo.Render(fmt.Sprintf("Socket %d: ", id)))
+		b.WriteString(m.styles.load(avg).Render(fmt.Sprintf("%.1f%%", avg)))
+		b.WriteString("\n")
+		b.WriteString(m.renderCPUCells(cores, perLine))
+	}
+	return b.String()
+}
+
+// renderCPUCells lays out bars for the listed cores, perLine to a line.
+// When there are more than fit in maxCPUGridLines lines, the rest share one
+// bar showing their average, along with the least and most busy of them.
+func (m model) renderCPUCells(cores []int, perLine int) string {
+	var rest []int
+	if limit := perLine * maxCPUGridLines; len(cores) > limit {
+		// Keep a grid line free for the summary
+		limit -= perLine
+		cores, rest = cores[:limit], cores[limit:]
+	}
+
+	var b strings.Builder
+	for i, core := range cores {
+		usage := m.stats.cpuPercent[core]
+		b.WriteString(fmt.Sprintf("%2d ", core))
+		b.WriteString(m.renderCPUBar(usage, cpuBarWidth))
+		b.WriteString(fmt.Sprintf(" %5.1f%%", usage))
+
+		if (i+1)%perLine == 0 || i == len(cores)-1 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString("  ")
+		}
+	}
 
+	if len(rest) > 0 {
+		loads := make([]float64, len(rest))
+		for i, core := range rest {
+			loads[i] = m.stats.cpuPercent[core]
+		}
+		avg := averageCPU(loads)
+		low, high := slices.Min(loads), slices.Max(loads)
+		// Sockets may hold cores that aren't numbered in one run
+		label := fmt.Sprintf("%d-%d", rest[0], rest[len(rest)-1])
+		if rest[len(rest)-1]-rest[0] != len(rest)-1 {
+			label = fmt.Sprintf("%d more", len(rest))
+		}
+		b.WriteString(label + " ")
+		b.WriteString(m.renderCPUBar(avg, cpuBarWidth))
+		b.WriteString(fmt.Sprintf(" avg %.0f%% min %.0f%% max ", avg, low))
+		b.WriteString(m.styles.load(high).Render(fmt.Sprintf("%.0f%%", high)))
+		b.WriteString("\n")
+	}
 	return b.String()
 }
 
+// colorizeTable styles cells of the rendered table: CPU% and MEM% by
+// severity, PID and UPTIME of processes that just started, and
+// USER/COMMAND when they match the search. The table truncates
+// cell values by counting the bytes of any embedded escape codes as visible
+// width, so styling is applied to the rendered output instead, using the
+// column layout to find each cell and the PID cell to find its process.
+// Lines that already carry styling, such as the selected row, are left alone
+// so their highlight isn't cut short by a reset.
+func (m model) colorizeTable(view string) string {
+	type span struct {
+		title      string
+		start, end int
+	}
+	var spans []span
+	pidSpan := -1
+
+	offset := 0
+	for _, col := range m.table.Columns() {
+		if col.Width <= 0 {
+			continue
+		}
+		// Each cell has one column of padding on either side
+		if col.Title == "PID" {
+			pidSpan = len(spans)
+		}
+		spans = append(spans, span{col.Title, offset + 1, offset + 1 + col.Width})
+		offset += col.Width + 2
+	}
+
+	procs := make(map[int32]ProcessInfo, len(m.rows))
+	for _, proc := range m.rows {
+		procs[proc.PID] = proc
+	}
+
+	lines := strings.Split(view, "\n")
+	// The first two lines are the header and its bottom border
+	for i := 2; i < len(lines); i++ {
+		if strings.Contains(lines[i], "\x1b") {
+			continue
+		}
+		// Spans are in terminal cells, which a wide character such as a
+		// CJK one in a command name takes two of
+		runes := []rune(lines[i])
+		at := cellIndex(runes)
+
+		var proc ProcessInfo
+		if pidSpan >= 0 && spans[pidSpan].end < len(at) {
+			sp := spans[pidSpan]
+			pid, err := strconv.Atoi(strings.TrimSpace(string(runes[at[sp.start]:at[sp.end]])))
+			if err != nil {
+				continue // padding below the last row
+			}
+			proc = procs[int32(pid)]
+		}
+
+		// Watched processes stand out across the whole row
+		if m.watchEntry(proc) != "" {
+			lines[i] = m.styles.watched.Render(lines[i])
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, sp := range spans {
+			if sp.end >= len(at) {
+				break
+			}
+			start, end := at[sp.start], at[sp.end]
+			cell := string(runes[start:end])
+			style, ok := m.cellStyle(sp.title, cell, proc)
+			if !ok {
+				continue
+			}
+			b.WriteString(string(runes[last:start]))
+			b.WriteString(style.Render(cell))
+			last = end
+		}
+		b.WriteString(string(runes[last:]))
+		lines[i] = b.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// cellIndex maps the terminal cells of a line to its runes: entry n is the
+// index of the rune drawn in cell n, with a final entry for the end of the
+// line. Zero-width runes such as combining marks stay with the rune before.
+func cellIndex(runes []rune) []int {
+	at := make([]int, 0, len(runes)+1)
+	for i, r := range runes {
+		for range lipgloss.Width(string(r)) {
+			at = append(at, i)
+		}
+	}
+	return append(at, len(runes))
+}
+
+// cellStyle returns the style for a rendered table cell, or false if the
+// cell should keep the table's default look.
+func (m model) cellStyle(title, cell string, proc ProcessInfo) (lipgloss.Style, bool) {
+	switch title {
+	case "CPU%", "MEM%":
+		percent, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+		if err == nil && percent >= 50 {
+			return m.styles.load(percent), true
+		}
+	case "PID", "UPTIME":
+		if proc.StartTime > 0 && proc.uptime(m.stats.sampledAt) < youngAge {
+			return m.styles.young, true
+		}
+	case "STATUS":
+		if statusCategory(proc.Status) == "blocked" {
+			return m.styles.cpuMid, true
+		}
+	case "USER":
+		if m.search != "" && containsFold(proc.User, m.search) {
+			return m.styles.search, true
+		}
+	case "COMMAND":
+		if m.search != "" && containsFold(m.command(proc), m.search) {
+			return m.styles.search, true
+		}
+	}
+	return lipgloss.Style{}, false
+}
+
+// renderCPUBar draws a bracketed bar of the given width, filled in
+// proportion to percent and colored by load.
+func (m model) renderCPUBar(percent float64, width int) string {
+	filled := int(percent/100*float64(width) + 0.5)
+	if filled < 0 {
+		filled = 0
+	} else if filled > width {
+		filled = width
+	}
+
+	bar := m.styles.load(percent).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Faint(true).Render(strings.Repeat("░", width-filled))
+	return "[" + bar + "]"
+}
+
+// renderStateBar draws a bar split into one segment per process state,
+// each as wide as that state's share of the processes, followed by a
+// legend with the counts.
+func (m model) renderStateBar(tasks taskCounts) string {
+	states := []struct {
+		letter string
+		count  int
+		style  lipgloss.Style
+	}{
+		{"R", tasks.running, m.styles.cpuLow},
+		{"S", tasks.sleeping - tasks.blocked, lipgloss.NewStyle().Faint(true)},
+		{"D", tasks.blocked, m.styles.cpuMid},
+		{"T", tasks.stopped, m.styles.search},
+		{"Z", tasks.zombie, m.styles.cpuHigh},
+	}
+	var total int
+	for _, state := range states {
+		total += state.count
+	}
+
+	// Segments end where the running total falls on the bar, so rounding
+	// never leaves it short or long
+	var bar, legend strings.Builder
+	var sum, drawn int
+	for _, state := range states {
+		sum += state.count
+		end := 0
+		if total > 0 {
+			end = (sum*stateBarWidth + total/2) / total
+		}
+		bar.WriteString(state.style.Render(strings.Repeat("█", end-drawn)))
+		drawn = end
+		legend.WriteString(" " + state.style.Render(fmt.Sprintf("%s %d", state.letter, state.count)))
+	}
+	// Only when no process is in a known state
+	bar.WriteString(lipgloss.NewStyle().Faint(true).Render(strings.Repeat("░", stateBarWidth-drawn)))
+	return "[" + bar.String() + "]" + legend.String()
+}
+
+// formatCPUTime renders seconds of CPU time the way top's TIME+ column
+// does, as minutes, seconds and hundredths such as "12:03.45".
+func formatCPUTime(seconds float64) string {
+	cs := int64(seconds*100 + 0.5)
+	return fmt.Sprintf("%d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours()) / 24
 	hours := int(d.Hours()) % 24
@@ -353,9 +3147,329 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dm", minutes)
 }
 
+// formatBytes renders a byte count in a compact human-readable form such
+// as "412M" or "1.2G".
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return strconv.FormatUint(b, 10)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	v := float64(b) / float64(div)
+	suffix := "KMGTPE"[exp]
+	if v >= 10 {
+		return fmt.Sprintf("%.0f%c", v, suffix)
+	}
+	return fmt.Sprintf("%.1f%c", v, suffix)
+}
+
+// formatMem renders a memory size in the unit chosen with --mem-unit.
+func (m model) formatMem(b uint64) string {
+	switch m.memUnit {
+	case "mb":
+		return fmt.Sprintf("%.0fM", float64(b)/(1024*1024))
+	case "auto":
+		return formatBytes(b)
+	}
+	return fmt.Sprintf("%.1fG", float64(b)/(1024*1024*1024))
+}
+
+// formatRate renders a bytes-per-second rate such as "1.2MB/s".
+func formatRate(bps float64) string {
+	return formatBytes(uint64(bps)) + "B/s"
+}
+
+// formatCount renders a count such as open file descriptors, or "-" when
+// it is unknown.
+func formatCount(n int32) string {
+	if n < 0 {
+		return "-"
+	}
+	return strconv.Itoa(int(n))
+}
+
+// formatIORate renders a per-process disk rate such as "2.1M/s", or "-"
+// when the process's I/O counters are unavailable.
+func formatIORate(bps float64) string {
+	if bps < 0 {
+		return "-"
+	}
+	return formatBytes(uint64(bps)) + "/s"
+}
+
+// formatProcessUptime renders how long a process has been running in a
+// compact form such as "2h13m", or "?" when the start time is unknown.
+func formatProcessUptime(proc ProcessInfo, now time.Time) string {
+	if proc.StartTime == 0 {
+		return "?"
+	}
+
+	d := proc.uptime(now)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, hours)
+	} else if hours > 0 {
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	} else if minutes > 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	opts := loadConfig(options{
+		sortBy:   "cpu",
+		thenBy:   "pid",
+		alerts:   alerts{ticks: defaultAlertTicks},
+		interval: defaultInterval,
+		maxRows:  defaultMaxRows,
+		theme:    defaultTheme,
+	})
+
+	flag.DurationVar(&opts.interval, "interval", opts.interval, "refresh interval (e.g. 500ms, 5s)")
+	flag.DurationVar(&opts.interval, "i", opts.interval, "shorthand for --interval")
+	flag.DurationVar(&opts.sortEvery, "sort-interval", 0, "re-sort the table at most this often, updating figures in place in between (0 re-sorts on every refresh)")
+	flag.IntVar(&opts.maxRows, "max-processes", opts.maxRows, "most processes per page, flipped with PgUp/PgDn (0 fills pages to the table height)")
+	flag.StringVar(&opts.exportFormat, "export-format", "csv", "snapshot export format: csv or json")
+	flag.BoolVar(&opts.confirmQuit, "confirm-quit", opts.confirmQuit, "ask before quitting with q; ctrl+c still quits at once")
+	flag.BoolVar(&opts.noRedact, "no-redact", false, "keep secret-looking environment variables in process dumps written from the detail view")
+	flag.IntVar(&opts.precision, "precision", 1, "decimal places shown for CPU% (0-3)")
+	flag.StringVar(&opts.memUnit, "mem-unit", "gb", "unit for memory and swap in the header and --once output: mb, gb or auto")
+	flag.BoolVar(&opts.showGPU, "gpu", false, "show NVIDIA GPU utilization (requires nvidia-smi)")
+	flag.BoolVar(&opts.showPSI, "psi", false, "show CPU, memory and I/O pressure stall averages (Linux 4.20+)")
+	flag.BoolVar(&opts.showConns, "connections", false, "show a CONN column with each process's open network connections (slow on busy systems)")
+	flag.BoolVar(&opts.showCgroups, "cgroups", false, "show a CGROUP column with each process's container ID or systemd unit (Linux)")
+	flag.BoolVar(&opts.showSwap, "swap", false, "show a SWAP column with how much of each process is swapped out (Linux)")
+	flag.StringVar(&opts.user, "user", "", "only show processes owned by this user")
+	flag.StringVar(&opts.user, "u", "", "shorthand for --user")
+	pidList := flag.String("pid", "", "comma-separated PIDs to show, hiding every other process")
+	flag.BoolVar(&opts.pidKids, "children", false, "with --pid, also show the listed processes' descendants")
+	filterRegex := flag.String("filter-regex", "", "only show processes whose command line matches this regular expression; type / then ~ for the same interactively")
+	watch := flag.String("watch", strings.Join(opts.watch, ","), "comma-separated process names to highlight, with a notice when one stops")
+	hidePanels := flag.String("hide-panels", strings.Join(opts.hidePanels, ","), "comma-separated header panels to hide: "+strings.Join(headerPanels, ", "))
+	columns := flag.String("columns", "", "comma-separated columns to show, in order: "+strings.Join(columnKeys(), ", "))
+	flag.Float64Var(&opts.alerts.cpuLimit, "alert-cpu", 0, "flash an alert when total CPU% stays at or above this (0 disables)")
+	flag.Float64Var(&opts.alerts.memLimit, "alert-mem", 0, "flash an alert when memory use stays at or above this percentage (0 disables)")
+	flag.IntVar(&opts.alerts.ticks, "alert-ticks", opts.alerts.ticks, "refreshes in a row a limit must be exceeded before alerting")
+	flag.BoolVar(&opts.alerts.beep, "alert-beep", false, "ring the terminal bell when an alert is raised")
+	flag.StringVar(&opts.alerts.command, "on-alert", "", "shell command to run when an alert is raised, with the details in XTOP_ALERT, XTOP_ALERT_KIND, XTOP_ALERT_CPU and XTOP_ALERT_MEM; failures are shown in the status line only")
+	flag.DurationVar(&opts.alerts.cooldown, "alert-cooldown", defaultAlertCooldown, "least time between runs of the --on-alert command while an alert stays raised")
+	logPath := flag.String("log", "", "append system-wide metrics to this CSV file on every refresh, in the TUI or with --json-stream")
+	flag.BoolVar(&opts.once, "once", false, "print a single snapshot to stdout and exit")
+	flag.BoolVar(&opts.jsonStream, "json-stream", false, "write a JSON snapshot to stdout every interval, one per line, instead of the TUI")
+	flag.BoolVar(&opts.hideIdle, "hide-idle", false, "hide processes with no CPU and negligible memory use")
+	flag.BoolVar(&opts.hideKernel, "hide-kernel", false, "hide kernel threads (Linux)")
+	flag.BoolVar(&opts.compact, "compact", false, "start in the compact view: no header panels and only the PID, CPU% and COMMAND columns")
+	flag.StringVar(&opts.theme, "theme", opts.theme, "color theme: "+strings.Join(themeNames(opts.themes), ", "))
+	flag.BoolVar(&opts.noColor, "no-color", os.Getenv("NO_COLOR") != "", "draw without colors, using only bold, underline and reverse video (also set by NO_COLOR)")
+	thenBy := flag.String("then-by", opts.thenBy, "tiebreak sort column for rows that are equal on the sort column")
+	sortBy := flag.String("sort", "", "sort column: "+strings.Join(sortKeys, ", "))
+	ascending := flag.Bool("ascending", false, "sort in ascending order; the default depends on the column, and --ascending=false forces descending")
+	flag.Parse()
+
+	if opts.interval < minInterval {
+		fmt.Fprintf(os.Stderr, "Error: interval %s is too short, must be at least %s\n", opts.interval, minInterval)
+		os.Exit(2)
+	}
+	if opts.maxRows < 0 {
+		fmt.Fprintf(os.Stderr, "Error: max-processes must not be negative\n")
+		os.Exit(2)
+	}
+	if opts.sortEvery < 0 {
+		fmt.Fprintf(os.Stderr, "Error: sort-interval must not be negative\n")
+		os.Exit(2)
+	}
+	if opts.precision < 0 || opts.precision > 3 {
+		fmt.Fprintf(os.Stderr, "Error: precision must be between 0 and 3\n")
+		os.Exit(2)
+	}
+	if opts.memUnit != "mb" && opts.memUnit != "gb" && opts.memUnit != "auto" {
+		fmt.Fprintf(os.Stderr, "Error: mem-unit must be mb, gb or auto, got %q\n", opts.memUnit)
+		os.Exit(2)
+	}
+	if opts.showPSI && !psiSupported() {
+		fmt.Fprintf(os.Stderr, "Error: --psi needs a Linux kernel with pressure stall information (/proc/pressure)\n")
+		os.Exit(2)
+	}
+	if opts.exportFormat != "csv" && opts.exportFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: export-format must be csv or json, got %q\n", opts.exportFormat)
+		os.Exit(2)
+	}
+	opts.watch = parseWatchList(*watch)
+	if panels, err := parsePanels(*hidePanels); err == nil {
+		opts.hidePanels = panels
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *pidList != "" {
+		pids, err := parsePIDList(*pidList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: pid: %v\n", err)
+			os.Exit(2)
+		}
+		opts.pids = pids
+	}
+	if opts.pidKids && opts.pids == nil {
+		fmt.Fprintf(os.Stderr, "Error: --children only applies with --pid\n")
+		os.Exit(2)
+	}
+	if *filterRegex != "" {
+		re, err := regexp.Compile(*filterRegex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: filter-regex: %v\n", err)
+			os.Exit(2)
+		}
+		opts.filterRe = re
+	}
+	if *columns != "" {
+		keys, err := parseColumns(*columns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		opts.columns = keys
+	}
+	// Asking for the CONN column turns on collecting connections
+	if slices.Contains(opts.columns, "conns") {
+		opts.showConns = true
+	}
+	if slices.Contains(opts.columns, "cgroup") {
+		opts.showCgroups = true
+	}
+	if slices.Contains(opts.columns, "swap") {
+		opts.showSwap = true
+	}
+	if _, ok := lookupTheme(opts.theme, opts.themes); !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown theme %q\n", opts.theme)
+		os.Exit(2)
+	}
+	if *sortBy != "" {
+		key, err := parseSortKey(*sortBy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		if key == "conns" && !opts.showConns {
+			fmt.Fprintf(os.Stderr, "Error: sorting by conns requires --connections\n")
+			os.Exit(2)
+		}
+		if key == "swap" && !opts.showSwap {
+			fmt.Fprintf(os.Stderr, "Error: sorting by swap requires --swap\n")
+			os.Exit(2)
+		}
+		opts.sortBy = key
+		opts.ascending = defaultAscending(key)
+	}
+	// Only an explicit --ascending overrides the column's default direction
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "ascending" {
+			opts.ascending = *ascending
+		}
+	})
+	// These flags aren't written back to the config file on exit
+	opts.oneOff = make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "watch", "columns", "hide-panels", "confirm-quit":
+			opts.oneOff[f.Name] = true
+		}
+	})
+	if opts.alerts.cpuLimit < 0 || opts.alerts.memLimit < 0 || opts.alerts.ticks < 1 {
+		fmt.Fprintf(os.Stderr, "Error: alert limits must not be negative and alert-ticks must be at least 1\n")
+		os.Exit(2)
+	}
+	if opts.alerts.cooldown < 0 {
+		fmt.Fprintf(os.Stderr, "Error: alert-cooldown must not be negative\n")
+		os.Exit(2)
+	}
+	if opts.alerts.command != "" && opts.alerts.cpuLimit == 0 && opts.alerts.memLimit == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --on-alert needs --alert-cpu or --alert-mem\n")
+		os.Exit(2)
+	}
+	// The config file's tiebreak column was already checked when loaded
+	if *thenBy != opts.thenBy {
+		key, err := parseSortKey(*thenBy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: tiebreak: %v\n", err)
+			os.Exit(2)
+		}
+		opts.thenBy = key
+	}
+	// A saved sort on the connections or swap column doesn't apply without it
+	if opts.sortBy == "conns" && !opts.showConns || opts.sortBy == "swap" && !opts.showSwap {
+		opts.sortBy = "cpu"
+		opts.ascending = defaultAscending("cpu")
+	}
+
+	if opts.once && opts.jsonStream {
+		fmt.Fprintf(os.Stderr, "Error: --once and --json-stream can't be used together\n")
+		os.Exit(2)
+	}
+
+	// The TUI needs a terminal; when piped or run from a script print a
+	// snapshot instead
+	if !opts.once && !opts.jsonStream {
+		fd := os.Stdout.Fd()
+		if !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+			fmt.Fprintln(os.Stderr, "xtop: stdout is not a terminal, printing a single snapshot (as with --once)")
+			opts.once = true
+		}
+	}
+
+	if opts.once {
+		if err := runOnce(os.Stdout, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *logPath != "" {
+		metrics, err := openMetricsLog(*logPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.metrics = metrics
+	}
+
+	if opts.jsonStream {
+		err := runJSONStream(os.Stdout, opts)
+		opts.metrics.close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Termination signals are handled here rather than by Bubble Tea so
+	// they quit the same way as the q key, saving the config. A second
+	// signal falls back to the default behavior in case the first one
+	// couldn't be handled.
+	p := tea.NewProgram(initialModel(opts), tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithoutSignalHandler())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)
+		p.Send(signalMsg{sig: sig})
+	}()
+
+	_, err := p.Run()
+	// Covers exits that don't go through quit
+	opts.metrics.close()
+	if err != nil {
 		fmt.Printf("Error running program: %v", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"time"
+)
+
+// metricsFlushInterval bounds how much of the metrics log is held in
+// memory, and so how much is lost if xtop is killed.
+const metricsFlushInterval = 10 * time.Second
+
+// metricsLog appends one CSV row of system-wide figures per refresh.
+type metricsLog struct {
+	f         *os.File
+	w         *csv.Writer
+	lastFlush time.Time
+}
+
+// openMetricsLog opens path for appending, writing the header row if the
+// file is new or empty.
+func openMetricsLog(path string) (*metricsLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &metricsLog{f: f, w: csv.NewWriter(f), lastFlush: time.Now()}
+	if info.Size() == 0 {
+		header := []string{"timestamp", "cpu_percent", "mem_percent", "load1", "load5", "load15", "processes"}
+		if err := l.w.Write(header); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// write buffers a row for stats and flushes once metricsFlushInterval has
+// passed since the last flush. Figures that couldn't be collected are left
+// blank. Write errors, such as a full disk, may only be reported by the
+// flush.
+func (l *metricsLog) write(stats systemStats) error {
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+
+	record := []string{stats.sampledAt.Format(time.RFC3339), "", "", "", "", "", ""}
+	if len(stats.cpuPercent) > 0 {
+		record[1] = formatFloat(stats.cpuTotal)
+	}
+	if stats.memStats != nil {
+		record[2] = formatFloat(stats.memStats.UsedPercent)
+	}
+	if stats.loadAvg != nil {
+		record[3] = formatFloat(stats.loadAvg.Load1)
+		record[4] = formatFloat(stats.loadAvg.Load5)
+		record[5] = formatFloat(stats.loadAvg.Load15)
+	}
+	if stats.errs["process list"] == nil {
+		record[6] = strconv.Itoa(len(stats.processInfo))
+	}
+
+	if err := l.w.Write(record); err != nil {
+		return err
+	}
+	if time.Since(l.lastFlush) < metricsFlushInterval {
+		return nil
+	}
+	l.lastFlush = time.Now()
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// close flushes any buffered rows and closes the file. It is safe to call
+// more than once.
+func (l *metricsLog) close() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	l.w.Flush()
+	err := l.w.Error()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,8 @@
+package main
+
+// niceFromPriority converts the value gopsutil reports for Process.Nice on
+// Linux, which is the raw kernel priority of 20 minus the niceness, back to
+// the usual -20..19 niceness.
+func niceFromPriority(raw int32) int32 {
+	return 20 - raw
+}
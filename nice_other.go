@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// niceFromPriority returns raw unchanged; outside Linux gopsutil already
+// reports the niceness itself.
+func niceFromPriority(raw int32) int32 {
+	return raw
+}
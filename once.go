@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// onceSampleDelay is the gap between the two samples taken by --once, long
+// enough for per-process CPU% to reflect recent activity.
+const onceSampleDelay = 500 * time.Millisecond
+
+// runOnce collects a snapshot, prints it as plain text to w and returns
+// without starting the TUI.
+func runOnce(w io.Writer, opts options) error {
+	m := initialModel(opts)
+
+	// Per-process CPU% and disk rates are derived from the difference between two samples
+	m.stats = collectStats(opts.showGPU, opts.showPSI, opts.showConns, opts.showCgroups, opts.showSwap, m.meta)
+	m.updateProcessRates()
+	time.Sleep(onceSampleDelay)
+	m.stats = collectStats(opts.showGPU, opts.showPSI, opts.showConns, opts.showCgroups, opts.showSwap, m.meta)
+	m.updateProcessRates()
+	m.updateTable()
+
+	return printSnapshot(w, m)
+}
+
+// printSnapshot writes a system summary followed by an aligned table of
+// the model's rows, in its column set.
+func printSnapshot(w io.Writer, m model) error {
+	stats := m.stats
+	if stats.uptime > 0 {
+		fmt.Fprintf(w, "Uptime: %s  ", formatDuration(stats.uptime))
+	}
+	if stats.loadAvg != nil {
+		fmt.Fprintf(w, "Load: %.2f %.2f %.2f  ",
+			stats.loadAvg.Load1, stats.loadAvg.Load5, stats.loadAvg.Load15)
+	}
+	fmt.Fprintf(w, "CPUs: %d\n", runtime.NumCPU())
+
+	if stats.memStats != nil {
+		fmt.Fprintf(w, "Memory: %s/%s (%.1f%%)\n",
+			m.formatMem(stats.memStats.Used), m.formatMem(stats.memStats.Total),
+			stats.memStats.UsedPercent)
+	}
+	if len(stats.psi) > 0 {
+		fmt.Fprint(w, "PSI:")
+		for _, stat := range stats.psi {
+			fmt.Fprintf(w, " %s %.1f/%.1f/%.1f", stat.resource, stat.avg10, stat.avg60, stat.avg300)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, name := range stats.failedSubsystems() {
+		fmt.Fprintf(w, "%s unavailable: %v\n", name, stats.errs[name])
+	}
+	fmt.Fprintln(w)
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	titles := make([]string, len(m.cols))
+	for i, spec := range m.cols {
+		titles[i] = spec.title
+	}
+	fmt.Fprintln(tw, strings.Join(titles, "\t"))
+	for _, proc := range m.rows {
+		cells := make([]string, len(m.cols))
+		for i, spec := range m.cols {
+			cells[i] = spec.cell(m, proc, now)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// headerPanels are the header sections that can be hidden, in the order of
+// the keys that toggle them.
+var headerPanels = []string{"uptime", "load", "cpu", "memory"}
+
+// panelKeys maps each toggle key to the header panel it shows or hides.
+var panelKeys = map[string]string{"2": "uptime", "3": "load", "4": "cpu", "5": "memory"}
+
+// parsePanels splits a comma-separated list of header panels such as
+// "uptime,load", rejecting unknown names.
+func parsePanels(list string) ([]string, error) {
+	var panels []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(headerPanels, name) {
+			return nil, fmt.Errorf("unknown panel %q (valid panels: %s)", name, strings.Join(headerPanels, ", "))
+		}
+		if !slices.Contains(panels, name) {
+			panels = append(panels, name)
+		}
+	}
+	return panels, nil
+}
+
+// showPanel reports whether the header panel called name is shown.
+func (m model) showPanel(name string) bool {
+	return !slices.Contains(m.hidePanels, name)
+}
+
+// togglePanel hides the header panel called name if it is shown, and shows
+// it otherwise. The panels shown are saved on exit from then on, even if
+// they were first given by --hide-panels.
+func (m *model) togglePanel(name string) {
+	delete(m.oneOff, "hide-panels")
+	if i := slices.Index(m.hidePanels, name); i >= 0 {
+		m.hidePanels = slices.Delete(slices.Clone(m.hidePanels), i, i+1)
+	} else {
+		m.hidePanels = append(slices.Clone(m.hidePanels), name)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePIDList parses a comma-separated list of PIDs such as "1234,5678".
+func parsePIDList(list string) ([]int32, error) {
+	var pids []int32
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		pid, err := strconv.ParseInt(field, 10, 32)
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("invalid PID %q", field)
+		}
+		pids = append(pids, int32(pid))
+	}
+	return pids, nil
+}
+
+// pidScope returns the PIDs the table is limited to by --pid, along with
+// all their descendants when --children is set, or nil when every process
+// is shown.
+func (m model) pidScope() map[int32]bool {
+	if len(m.pids) == 0 {
+		return nil
+	}
+	scope := make(map[int32]bool, len(m.pids))
+	for _, pid := range m.pids {
+		scope[pid] = true
+	}
+	if !m.pidKids {
+		return scope
+	}
+
+	children := make(map[int32][]int32)
+	for _, proc := range m.stats.processInfo {
+		children[proc.PPID] = append(children[proc.PPID], proc.PID)
+	}
+	queue := append([]int32(nil), m.pids...)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			if !scope[child] {
+				scope[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return scope
+}
+
+// missingPIDs returns the PIDs given with --pid that aren't running.
+func (m model) missingPIDs() []string {
+	running := make(map[int32]bool, len(m.stats.processInfo))
+	for _, proc := range m.stats.processInfo {
+		running[proc.PID] = true
+	}
+	var missing []string
+	for _, pid := range m.pids {
+		if !running[pid] {
+			missing = append(missing, strconv.Itoa(int(pid)))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"syscall"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// listeningPort is a TCP socket in the LISTEN state or an unconnected UDP
+// socket. pid is 0 when the owner can't be determined, such as another
+// user's socket without permission.
+type listeningPort struct {
+	proto string
+	addr  string
+	port  uint32
+	pid   int32
+}
+
+// portsMsg carries a freshly gathered list of listening ports.
+type portsMsg struct {
+	ports []listeningPort
+	err   error
+}
+
+// fetchPorts lists the listening IPv4 and IPv6 ports, ordered by protocol
+// and port.
+func fetchPorts() tea.Cmd {
+	return func() tea.Msg {
+		conns, err := psnet.Connections("inet")
+		if err != nil {
+			return portsMsg{err: err}
+		}
+
+		var ports []listeningPort
+		seen := make(map[listeningPort]bool)
+		for _, conn := range conns {
+			var proto string
+			switch {
+			case conn.Type == syscall.SOCK_STREAM && conn.Status == "LISTEN":
+				proto = "tcp"
+			case conn.Type == syscall.SOCK_DGRAM && conn.Raddr.Port == 0:
+				proto = "udp"
+			default:
+				continue
+			}
+			if conn.Family == syscall.AF_INET6 {
+				proto += "6"
+			}
+
+			port := listeningPort{proto: proto, addr: conn.Laddr.IP, port: conn.Laddr.Port, pid: conn.Pid}
+			// A socket shared by forked workers is listed once per process
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+
+		slices.SortFunc(ports, func(a, b listeningPort) int {
+			return cmp.Or(cmp.Compare(a.proto, b.proto), cmp.Compare(a.port, b.port),
+				cmp.Compare(a.addr, b.addr), cmp.Compare(a.pid, b.pid))
+		})
+		return portsMsg{ports: ports}
+	}
+}
+
+// portColumns sizes the listening ports table to the terminal width, with
+// COMMAND taking whatever is left.
+func portColumns(width int) []table.Column {
+	columns := []table.Column{
+		{Title: "PROTO", Width: 5},
+		{Title: "ADDR:PORT", Width: 30},
+		{Title: "PID", Width: 8},
+		{Title: "COMMAND", Width: 30},
+	}
+	if width > 0 {
+		// Border and cell padding as for the process table
+		used := 4 + 2*len(columns)
+		for _, column := range columns[:len(columns)-1] {
+			used += column.Width
+		}
+		columns[len(columns)-1].Width = max(width-used, 10)
+	}
+	return columns
+}
+
+// openPorts switches to the listening ports view.
+func (m model) openPorts() (tea.Model, tea.Cmd) {
+	m.mode = viewPorts
+	return m, fetchPorts()
+}
+
+// updatePorts captures key presses while the listening ports view is open.
+func (m model) updatePorts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "L":
+		m.mode = viewTable
+		return m, nil
+	case "q":
+		return m.requestQuit()
+	case "ctrl+c":
+		return m.quit()
+	}
+	var cmd tea.Cmd
+	m.ports, cmd = m.ports.Update(msg)
+	return m, cmd
+}
+
+// setPorts fills the listening ports table, naming each owner from the
+// latest process list.
+func (m *model) setPorts(msg portsMsg) {
+	m.portsErr = msg.err
+	if msg.err != nil {
+		return
+	}
+
+	names := make(map[int32]string, len(m.stats.processInfo))
+	for _, proc := range m.stats.processInfo {
+		names[proc.PID] = m.command(proc)
+	}
+
+	rows := make([]table.Row, 0, len(msg.ports))
+	for _, port := range msg.ports {
+		addr := port.addr
+		if addr == "" {
+			addr = "*"
+		}
+		pid, command := "-", "-"
+		if port.pid != 0 {
+			pid = strconv.Itoa(int(port.pid))
+			if name, ok := names[port.pid]; ok {
+				command = name
+			}
+		}
+		rows = append(rows, table.Row{
+			port.proto,
+			net.JoinHostPort(addr, strconv.FormatUint(uint64(port.port), 10)),
+			pid,
+			command,
+		})
+	}
+	m.ports.SetRows(rows)
+}
+
+// renderPorts renders the listening ports table.
+func (m model) renderPorts() string {
+	if m.portsErr != nil {
+		return m.styles.error.Render(fmt.Sprintf("Listening ports unavailable: %v", m.portsErr))
+	}
+	title := m.styles.systemInfo.Render(fmt.Sprintf("Listening ports (%d)", len(m.ports.Rows())))
+	return lipgloss.JoinVertical(lipgloss.Left, title, m.styles.processTable.Render(m.ports.View()))
+}
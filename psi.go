@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// psiWarnPercent is the stall share, in percent, above which a pressure
+// average is shown in the error color.
+const psiWarnPercent = 10
+
+// psiResources are the resources pressure stall information is read for,
+// in the order they are shown.
+var psiResources = []string{"cpu", "memory", "io"}
+
+// psiStat is the pressure on one resource: the share of time, in percent,
+// that at least one task was stalled waiting for it, averaged over the
+// last 10 seconds, 1 minute and 5 minutes.
+type psiStat struct {
+	resource string
+	avg10    float64
+	avg60    float64
+	avg300   float64
+}
+
+// peak returns the highest of the three averages.
+func (p psiStat) peak() float64 {
+	return max(p.avg10, p.avg60, p.avg300)
+}
+
+// parsePSI reads the "some" line of a /proc/pressure file, which looks like
+//
+//	some avg10=0.31 avg60=0.12 avg300=0.04 total=1234567
+//
+// The "full" line that follows for memory and I/O is ignored.
+func parsePSI(resource, data string) (psiStat, error) {
+	stat := psiStat{resource: resource}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		found := 0
+		for _, field := range fields[1:] {
+			name, value, _ := strings.Cut(field, "=")
+			var dst *float64
+			switch name {
+			case "avg10":
+				dst = &stat.avg10
+			case "avg60":
+				dst = &stat.avg60
+			case "avg300":
+				dst = &stat.avg300
+			default:
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return stat, fmt.Errorf("%s pressure: bad %s value %q", resource, name, value)
+			}
+			*dst = v
+			found++
+		}
+		if found < 3 {
+			return stat, fmt.Errorf("%s pressure: missing averages", resource)
+		}
+		return stat, nil
+	}
+	return stat, fmt.Errorf("%s pressure: no \"some\" line", resource)
+}
+
+// renderPSI renders the PSI header line, each resource showing its 10s,
+// 1m and 5m averages.
+func (m model) renderPSI() string {
+	var b strings.Builder
+	b.WriteString(m.styles.systemInfo.Render("PSI:"))
+	for _, stat := range m.stats.psi {
+		style := m.styles.systemInfo
+		if stat.peak() > psiWarnPercent {
+			style = m.styles.error
+		}
+		b.WriteString(" ")
+		b.WriteString(style.Render(fmt.Sprintf("%s %.1f/%.1f/%.1f",
+			stat.resource, stat.avg10, stat.avg60, stat.avg300)))
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// psiDir holds a file per resource on kernels built with pressure stall
+// information, 4.20 and later.
+const psiDir = "/proc/pressure"
+
+// psiSupported reports whether the kernel exposes pressure stall
+// information.
+func psiSupported() bool {
+	_, err := os.Stat(filepath.Join(psiDir, "cpu"))
+	return err == nil
+}
+
+// getPSI reads the pressure on every resource in psiResources.
+func getPSI() ([]psiStat, error) {
+	stats := make([]psiStat, 0, len(psiResources))
+	for _, resource := range psiResources {
+		data, err := os.ReadFile(filepath.Join(psiDir, resource))
+		if err != nil {
+			return nil, err
+		}
+		stat, err := parsePSI(resource, string(data))
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
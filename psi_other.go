@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// psiSupported always reports false; pressure stall information is a
+// Linux kernel feature.
+func psiSupported() bool {
+	return false
+}
+
+// getPSI is only implemented on Linux.
+func getPSI() ([]psiStat, error) {
+	return nil, errors.ErrUnsupported
+}
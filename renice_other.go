@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// setNice is not supported on this platform.
+func setNice(pid int32, nice int) error {
+	return errors.New("renice is not supported on this platform")
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// setNice sets the niceness of pid. Lowering it below the current value
+// usually requires root and fails with EPERM otherwise.
+func setNice(pid int32, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, int(pid), nice)
+}
@@ -0,0 +1,113 @@
+package main
+
+// groupSessions folds processes into one row per session, placed where the
+// session's first process was, so a service and all of its workers show up
+// as a single entry. The row keeps the session leader's details when the
+// leader is listed, and otherwise those of the first member, PID included,
+// so it never names a process that isn't there. Unlike threads, the processes of a session each have
+// their own memory, so every figure is summed. Processes whose session is
+// unknown, and kernel threads, stay on rows of their own.
+func groupSessions(procs []ProcessInfo) []ProcessInfo {
+	var order []int32
+	groups := make(map[int32][]ProcessInfo, len(procs))
+	for _, proc := range procs {
+		// Negative keys can't clash with a session ID
+		sid := proc.sid
+		if sid == 0 {
+			sid = -proc.PID
+		}
+		if _, ok := groups[sid]; !ok {
+			order = append(order, sid)
+		}
+		groups[sid] = append(groups[sid], proc)
+	}
+
+	grouped := make([]ProcessInfo, 0, len(order))
+	for _, sid := range order {
+		members := groups[sid]
+		if len(members) == 1 {
+			grouped = append(grouped, members[0])
+			continue
+		}
+
+		row := members[0]
+		for _, proc := range members {
+			if proc.PID == sid {
+				row = proc
+				break
+			}
+		}
+		grouped = append(grouped, sumProcesses(row, members))
+	}
+	return grouped
+}
+
+// sumProcesses returns row with its figures replaced by the sums of those
+// of members, for a row that stands for several whole processes.
+func sumProcesses(row ProcessInfo, members []ProcessInfo) ProcessInfo {
+	row.CPUPerc, row.MemPerc, row.MemRSS, row.Threads = 0, 0, 0, 0
+	row.CPUAvg, row.cpuTime = 0, 0
+	row.NumFDs, row.Conns, row.Swap = -1, -1, -1
+	row.DiskReadBps, row.DiskWriteBps = -1, -1
+
+	for _, proc := range members {
+		row.CPUPerc += proc.CPUPerc
+		row.CPUAvg += proc.CPUAvg
+		row.cpuTime += proc.cpuTime
+		row.MemPerc += proc.MemPerc
+		row.MemRSS += proc.MemRSS
+		row.Threads += proc.Threads
+		row.NumFDs = addCount(row.NumFDs, proc.NumFDs)
+		row.Conns = addCount(row.Conns, proc.Conns)
+		row.Swap = addCount(row.Swap, proc.Swap)
+		row.DiskReadBps = addRate(row.DiskReadBps, proc.DiskReadBps)
+		row.DiskWriteBps = addRate(row.DiskWriteBps, proc.DiskWriteBps)
+	}
+	row.members = len(members)
+	return row
+}
+
+// groupNames folds processes running the same command name into one row,
+// placed where the first of them was, so a pool of identical workers shows
+// up as a single entry with their figures summed. The row keeps the
+// details of the process with the lowest PID.
+func groupNames(procs []ProcessInfo) []ProcessInfo {
+	var order []string
+	groups := make(map[string][]ProcessInfo, len(procs))
+	for _, proc := range procs {
+		if _, ok := groups[proc.Name]; !ok {
+			order = append(order, proc.Name)
+		}
+		groups[proc.Name] = append(groups[proc.Name], proc)
+	}
+
+	grouped := make([]ProcessInfo, 0, len(order))
+	for _, name := range order {
+		members := groups[name]
+		if len(members) == 1 {
+			grouped = append(grouped, members[0])
+			continue
+		}
+
+		row := members[0]
+		for _, proc := range members {
+			if proc.PID < row.PID {
+				row = proc
+			}
+		}
+		grouped = append(grouped, sumProcesses(row, members))
+	}
+	return grouped
+}
+
+// addCount adds n to total, where -1 marks either as unavailable, in the
+// same way as addRate.
+func addCount[T int32 | int64](total, n T) T {
+	switch {
+	case n < 0:
+		return total
+	case total < 0:
+		return n
+	}
+	return total + n
+}
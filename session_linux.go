@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// sessionID returns the session a process belongs to, read from /proc
+// since gopsutil doesn't expose it. Kernel threads belong to session 0.
+func sessionID(pid int32) (int32, error) {
+	fields, err := statFields(pid)
+	if err != nil {
+		return 0, err
+	}
+	sid, err := strconv.ParseInt(string(fields[3]), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(sid), nil
+}
+
+// statFields reads /proc/<pid>/stat and returns its fields after the
+// command name, starting with the state. The command name is in
+// parentheses and may itself contain spaces or parentheses, so the fields
+// are counted from the last ')': state, ppid, pgrp, session, tty_nr.
+func statFields(pid int32) ([][]byte, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed stat for PID %d", pid)
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("malformed stat for PID %d", pid)
+	}
+	return fields, nil
+}
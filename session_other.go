@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// sessionID isn't implemented outside Linux, so every process is shown as
+// a group of its own.
+func sessionID(pid int32) (int32, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// signalChoice is an entry in the signal picker.
+type signalChoice struct {
+	name string
+	sig  syscall.Signal
+	desc string
+}
+
+// signalPicker is the signal menu opened on a process, with the cursor on
+// the signal that will be sent.
+type signalPicker struct {
+	pid    int32
+	name   string
+	cursor int
+}
+
+// openSignalPicker shows the signal menu for the selected process.
+func (m model) openSignalPicker() (tea.Model, tea.Cmd) {
+	if proc, ok := m.selectedTarget(); ok {
+		m.err = nil
+		m.picker = &signalPicker{pid: proc.PID, name: proc.Name}
+	}
+	return m, nil
+}
+
+// updateSignalPicker handles key presses while the signal menu is open.
+// Picking a signal asks for confirmation, except for SIGCONT, which only
+// resumes a stopped process.
+func (m model) updateSignalPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+	switch key := msg.String(); key {
+	case "up", "k":
+		p.cursor = (p.cursor - 1 + len(signalChoices)) % len(signalChoices)
+	case "down", "j":
+		p.cursor = (p.cursor + 1) % len(signalChoices)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if i := int(key[0] - '1'); i < len(signalChoices) {
+			p.cursor = i
+			return m.pickSignal()
+		}
+	case "enter":
+		return m.pickSignal()
+	case "esc", "q":
+		m.picker = nil
+	case "ctrl+c":
+		return m.quit()
+	}
+	return m, nil
+}
+
+// pickSignal closes the menu and sends the chosen signal, after asking
+// first if it could stop or end the process.
+func (m model) pickSignal() (tea.Model, tea.Cmd) {
+	choice := signalChoices[m.picker.cursor]
+	req := killRequest{pid: m.picker.pid, name: m.picker.name, sig: choice.sig, sigName: choice.name}
+	m.picker = nil
+	if choice.sig == sigCont {
+		return m, sendSignal(req)
+	}
+	m.confirm = &req
+	return m, nil
+}
+
+// renderSignalPicker draws the signal menu.
+func (m model) renderSignalPicker() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Send a signal to %s (PID %d)\n\n", m.picker.name, m.picker.pid))
+	for i, choice := range signalChoices {
+		line := fmt.Sprintf("[%d] %-8s %s", i+1, choice.name, choice.desc)
+		if i == m.picker.cursor {
+			line = m.styles.search.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n[↑/↓] Move   [enter] Send   [esc] Cancel")
+	return m.styles.confirm.Render(b.String())
+}
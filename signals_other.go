@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+// sigCont doesn't exist here; -1 matches no signal.
+const sigCont = syscall.Signal(-1)
+
+// signalChoices are the signals offered by the signal menu. Only ending a
+// process is supported on this platform.
+var signalChoices = []signalChoice{
+	{"SIGTERM", syscall.SIGTERM, "ask the process to exit"},
+	{"SIGKILL", syscall.SIGKILL, "end the process immediately"},
+}
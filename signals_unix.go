@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// sigCont is sent without confirmation, since it only resumes a process.
+const sigCont = syscall.SIGCONT
+
+// signalChoices are the signals offered by the signal menu.
+var signalChoices = []signalChoice{
+	{"SIGTERM", syscall.SIGTERM, "ask the process to exit"},
+	{"SIGKILL", syscall.SIGKILL, "end the process immediately"},
+	{"SIGHUP", syscall.SIGHUP, "hang up; many daemons reload their config"},
+	{"SIGSTOP", syscall.SIGSTOP, "pause the process"},
+	{"SIGCONT", syscall.SIGCONT, "resume a paused process"},
+	{"SIGUSR1", syscall.SIGUSR1, "user-defined, meaning depends on the program"},
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runJSONStream writes one JSON snapshot per refresh interval to w, each
+// on its own line, until interrupted. The process list is sorted, filtered
+// and capped as the table would be. The first snapshot is written after
+// one interval, once per-process rates can be worked out. With --log, each
+// refresh is also appended to the metrics log, which is given up with a
+// warning on stderr if writing to it fails.
+func runJSONStream(w io.Writer, opts options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := initialModel(opts)
+	m.stats = collectStats(opts.showGPU, opts.showPSI, opts.showConns, opts.showCgroups, opts.showSwap, m.meta)
+	m.updateProcessRates()
+
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		m.stats = collectStats(opts.showGPU, opts.showPSI, opts.showConns, opts.showCgroups, opts.showSwap, m.meta)
+		m.updateProcessRates()
+		m.updateTable()
+		if m.metrics != nil {
+			if err := m.metrics.write(m.stats); err != nil {
+				fmt.Fprintf(os.Stderr, "xtop: log disabled: %v\n", err)
+				m.metrics.close()
+				m.metrics = nil
+			}
+		}
+
+		// A closed pipe, such as the reader exiting, ends the stream
+		err := enc.Encode(snapshot{
+			System:    newSnapshotSystem(m.stats, m.stats.sampledAt),
+			Processes: m.rows,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
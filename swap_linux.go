@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processSwap reads how much of a process is swapped out from the VmSwap
+// line of /proc/<pid>/status, which is far cheaper than adding up its
+// smaps. It returns -1 when the file can't be read or has no such line, as
+// for kernel threads.
+func processSwap(pid int32) int64 {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The line reads "VmSwap:     1234 kB"
+		value, ok := strings.CutPrefix(scanner.Text(), "VmSwap:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return kb * 1024
+	}
+	return -1
+}
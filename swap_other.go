@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// processSwap always returns -1; per-process swap is only read on Linux.
+func processSwap(pid int32) int64 {
+	return -1
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultTheme is used when neither the config file nor --theme picks one.
+const defaultTheme = "dark"
+
+// Theme is the set of colors xtop draws with. Each value is a lipgloss
+// color, either an ANSI code such as "57" or a hex value such as "#5f00ff".
+// An empty value keeps the terminal's default color.
+type Theme struct {
+	HeaderFg   string `json:"headerFg,omitempty"`
+	HeaderBg   string `json:"headerBg,omitempty"`
+	SelectedFg string `json:"selectedFg,omitempty"`
+	SelectedBg string `json:"selectedBg,omitempty"`
+	Border     string `json:"border,omitempty"`
+	Accent     string `json:"accent,omitempty"`
+	Low        string `json:"low,omitempty"`
+	Mid        string `json:"mid,omitempty"`
+	High       string `json:"high,omitempty"`
+	Highlight  string `json:"highlight,omitempty"`
+	Watched    string `json:"watched,omitempty"`
+	Young      string `json:"young,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// themePresets are the built-in themes selectable with --theme.
+var themePresets = map[string]Theme{
+	"dark": {
+		HeaderFg:   "15",
+		HeaderBg:   "57",
+		SelectedFg: "229",
+		SelectedBg: "57",
+		Border:     "240",
+		Accent:     "10",
+		Low:        "10",
+		Mid:        "11",
+		High:       "9",
+		Highlight:  "13",
+		Watched:    "23",
+		Young:      "14",
+		Error:      "9",
+	},
+	"light": {
+		HeaderFg:   "15",
+		HeaderBg:   "25",
+		SelectedFg: "0",
+		SelectedBg: "153",
+		Border:     "245",
+		Accent:     "22",
+		Low:        "28",
+		Mid:        "130",
+		High:       "160",
+		Highlight:  "90",
+		Watched:    "194",
+		Young:      "30",
+		Error:      "160",
+	},
+	// mono relies on bold and reverse video instead of color
+	"mono": {},
+}
+
+// lookupTheme resolves name against the presets and the themes defined in
+// the config file. Config themes start from the dark preset, so they only
+// need to list the colors they change.
+func lookupTheme(name string, custom map[string]Theme) (Theme, bool) {
+	if t, ok := custom[name]; ok {
+		return themePresets[defaultTheme].merge(t), true
+	}
+	t, ok := themePresets[name]
+	return t, ok
+}
+
+// resolveTheme returns the theme chosen in opts with the config file's
+// color overrides applied, or the colorless mono theme with --no-color.
+func resolveTheme(opts options) Theme {
+	if opts.noColor {
+		return themePresets["mono"]
+	}
+	t, _ := lookupTheme(opts.theme, opts.themes)
+	return t.merge(opts.colors)
+}
+
+// themeNames lists the presets and config themes for help and error text.
+func themeNames(custom map[string]Theme) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range themePresets {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range custom {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// merge returns t with every non-empty color of o applied on top.
+func (t Theme) merge(o Theme) Theme {
+	pick := func(base, override string) string {
+		if override != "" {
+			return override
+		}
+		return base
+	}
+	return Theme{
+		HeaderFg:   pick(t.HeaderFg, o.HeaderFg),
+		HeaderBg:   pick(t.HeaderBg, o.HeaderBg),
+		SelectedFg: pick(t.SelectedFg, o.SelectedFg),
+		SelectedBg: pick(t.SelectedBg, o.SelectedBg),
+		Border:     pick(t.Border, o.Border),
+		Accent:     pick(t.Accent, o.Accent),
+		Low:        pick(t.Low, o.Low),
+		Mid:        pick(t.Mid, o.Mid),
+		High:       pick(t.High, o.High),
+		Highlight:  pick(t.Highlight, o.Highlight),
+		Watched:    pick(t.Watched, o.Watched),
+		Young:      pick(t.Young, o.Young),
+		Error:      pick(t.Error, o.Error),
+	}
+}
+
+// styles are the lipgloss styles built from the active theme.
+type styles struct {
+	header       lipgloss.Style
+	alert        lipgloss.Style
+	systemInfo   lipgloss.Style
+	processTable lipgloss.Style
+	cpuLow       lipgloss.Style
+	cpuMid       lipgloss.Style
+	cpuHigh      lipgloss.Style
+	search       lipgloss.Style
+	watched      lipgloss.Style
+	young        lipgloss.Style
+	error        lipgloss.Style
+	confirm      lipgloss.Style
+}
+
+// newStyles builds the styles for t. Without a background color the header
+// and the selected row fall back to reverse video, and without a high-load
+// color heavy usage is shown in bold, so a colorless theme stays readable.
+func newStyles(t Theme) styles {
+	return styles{
+		header: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(themeColor(t.HeaderFg)).
+			Background(themeColor(t.HeaderBg)).
+			Padding(0, 1).
+			Reverse(t.HeaderBg == ""),
+
+		alert: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(themeColor(t.HeaderFg)).
+			Background(themeColor(t.Error)).
+			Padding(0, 1).
+			Reverse(t.Error == ""),
+
+		systemInfo: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(themeColor(t.Accent)),
+
+		processTable: lipgloss.NewStyle().
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderForeground(themeColor(t.Border)),
+
+		cpuLow:  lipgloss.NewStyle().Foreground(themeColor(t.Low)),
+		cpuMid:  lipgloss.NewStyle().Foreground(themeColor(t.Mid)),
+		cpuHigh: lipgloss.NewStyle().Foreground(themeColor(t.High)).Bold(t.High == ""),
+
+		search: lipgloss.NewStyle().
+			Bold(true).
+			Underline(t.Highlight == "").
+			Foreground(themeColor(t.Highlight)),
+
+		watched: lipgloss.NewStyle().
+			Bold(true).
+			Underline(t.Watched == "").
+			Background(themeColor(t.Watched)),
+
+		young: lipgloss.NewStyle().
+			Bold(true).
+			Italic(t.Young == "").
+			Foreground(themeColor(t.Young)),
+
+		error: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(themeColor(t.Error)),
+
+		confirm: lipgloss.NewStyle().
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(themeColor(t.Error)).
+			Padding(0, 2),
+	}
+}
+
+// themeColor converts a theme value to a lipgloss color, with "" meaning
+// the terminal default.
+func themeColor(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// loadAvg picks the low, mid or high style for a load average on a machine
+// with cores CPUs: low while there is a CPU free for every runnable task,
+// mid up to half as many tasks again, and high beyond that.
+func (s styles) loadAvg(load float64, cores int) lipgloss.Style {
+	switch perCore := load / float64(max(cores, 1)); {
+	case perCore > 1.5:
+		return s.cpuHigh
+	case perCore >= 1:
+		return s.cpuMid
+	}
+	return s.cpuLow
+}
+
+// load picks the low, mid or high style for a utilization percentage.
+func (s styles) load(percent float64) lipgloss.Style {
+	switch {
+	case percent >= 80:
+		return s.cpuHigh
+	case percent >= 50:
+		return s.cpuMid
+	}
+	return s.cpuLow
+}
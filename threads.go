@@ -0,0 +1,69 @@
+package main
+
+// groupThreads folds entries that belong to the same thread group into a
+// single row per process, placed where the group's first entry was. The row
+// keeps the group leader's details when the leader is listed. CPU% and disk
+// rates are summed, while memory is shared by the threads of a process, so
+// the largest figure is kept rather than counting it once per thread; the
+// same goes for CPU time, which already covers every thread. Where
+// every entry is already its own process, procs is returned unchanged.
+func groupThreads(procs []ProcessInfo) []ProcessInfo {
+	var order []int32
+	groups := make(map[int32][]ProcessInfo, len(procs))
+	for _, proc := range procs {
+		tgid := proc.tgid
+		if tgid == 0 {
+			tgid = proc.PID
+		}
+		if _, ok := groups[tgid]; !ok {
+			order = append(order, tgid)
+		}
+		groups[tgid] = append(groups[tgid], proc)
+	}
+	if len(order) == len(procs) {
+		return procs
+	}
+
+	grouped := make([]ProcessInfo, 0, len(order))
+	for _, tgid := range order {
+		members := groups[tgid]
+
+		row := members[0]
+		for _, proc := range members {
+			if proc.PID == tgid {
+				row = proc
+				break
+			}
+		}
+		row.PID = tgid
+		row.CPUPerc, row.CPUAvg = 0, 0
+		row.DiskReadBps, row.DiskWriteBps = -1, -1
+
+		for _, proc := range members {
+			row.CPUPerc += proc.CPUPerc
+			row.CPUAvg += proc.CPUAvg
+			row.DiskReadBps = addRate(row.DiskReadBps, proc.DiskReadBps)
+			row.DiskWriteBps = addRate(row.DiskWriteBps, proc.DiskWriteBps)
+			row.MemPerc = max(row.MemPerc, proc.MemPerc)
+			row.MemRSS = max(row.MemRSS, proc.MemRSS)
+			row.Swap = max(row.Swap, proc.Swap)
+			row.cpuTime = max(row.cpuTime, proc.cpuTime)
+		}
+		row.Threads = max(row.Threads, int32(len(members)))
+
+		grouped = append(grouped, row)
+	}
+	return grouped
+}
+
+// addRate adds rate to total, where -1 marks either as unavailable. The sum
+// is only unavailable when neither side is known.
+func addRate(total, rate float64) float64 {
+	switch {
+	case rate < 0:
+		return total
+	case total < 0:
+		return rate
+	}
+	return total + rate
+}
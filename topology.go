@@ -0,0 +1,34 @@
+package main
+
+import (
+	"runtime"
+	"strconv"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// cpuSockets maps each logical CPU to the physical package, or socket, it
+// sits in. It returns nil when the topology can't be read, as on platforms
+// where cpu.Info reports whole packages rather than logical CPUs, or when
+// every CPU is in the same package, so the per-core bars stay a flat list.
+func cpuSockets() []int {
+	infos, err := cpu.Info()
+	if err != nil || len(infos) != runtime.NumCPU() {
+		return nil
+	}
+
+	sockets := make([]int, len(infos))
+	seen := make(map[int]bool)
+	for _, info := range infos {
+		id, err := strconv.Atoi(info.PhysicalID)
+		if err != nil || info.CPU < 0 || int(info.CPU) >= len(sockets) {
+			return nil
+		}
+		sockets[info.CPU] = id
+		seen[id] = true
+	}
+	if len(seen) < 2 {
+		return nil
+	}
+	return sockets
+}
@@ -0,0 +1,63 @@
+package main
+
+// treeEntry is a process placed in the tree view along with the drawing
+// prefix that shows its position under its parent.
+type treeEntry struct {
+	proc   ProcessInfo
+	prefix string
+}
+
+// buildTree orders procs depth-first so that children follow their parent.
+// Siblings keep the relative order they have in procs, so sorting is applied
+// within each sibling group. Processes whose parent isn't in procs are
+// treated as roots.
+func buildTree(procs []ProcessInfo) []treeEntry {
+	present := make(map[int32]bool, len(procs))
+	for _, proc := range procs {
+		present[proc.PID] = true
+	}
+
+	var roots []ProcessInfo
+	children := make(map[int32][]ProcessInfo)
+	for _, proc := range procs {
+		if proc.PPID == proc.PID || !present[proc.PPID] {
+			roots = append(roots, proc)
+			continue
+		}
+		children[proc.PPID] = append(children[proc.PPID], proc)
+	}
+
+	entries := make([]treeEntry, 0, len(procs))
+	visited := make(map[int32]bool, len(procs))
+
+	var walk func(proc ProcessInfo, indent, branch string)
+	walk = func(proc ProcessInfo, indent, branch string) {
+		if visited[proc.PID] {
+			return
+		}
+		visited[proc.PID] = true
+		entries = append(entries, treeEntry{proc: proc, prefix: indent + branch})
+
+		kids := children[proc.PID]
+		for i, child := range kids {
+			childIndent := indent
+			if branch == "├─" {
+				childIndent += "│ "
+			} else if branch == "└─" {
+				childIndent += "  "
+			}
+
+			if i == len(kids)-1 {
+				walk(child, childIndent, "└─")
+			} else {
+				walk(child, childIndent, "├─")
+			}
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, "", "")
+	}
+
+	return entries
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// processTTY returns the controlling terminal of a process as ps names
+// it, such as "pts/3", or "?" when it has none or it can't be read.
+func processTTY(pid int32) string {
+	fields, err := statFields(pid)
+	if err != nil {
+		return "?"
+	}
+	nr, err := strconv.ParseUint(string(fields[4]), 10, 32)
+	if err != nil {
+		return "?"
+	}
+	return ttyName(nr)
+}
+
+// ttyName turns the device number in the tty_nr field of /proc/<pid>/stat
+// into a device name. Pseudo-terminals, virtual consoles and serial ports
+// are named; other devices show as "major,minor".
+func ttyName(nr uint64) string {
+	if nr == 0 {
+		return "?"
+	}
+	major := (nr >> 8) & 0xfff
+	minor := nr&0xff | (nr>>12)&0xfff00
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	}
+	return fmt.Sprintf("%d,%d", major, minor)
+}
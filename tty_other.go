@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// processTTY always returns "?"; the controlling terminal is only read on
+// Linux.
+func processTTY(pid int32) string {
+	return "?"
+}
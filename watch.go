@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// parseWatchList splits a comma-separated list of process names such as
+// "nginx,postgres", dropping blank entries.
+func parseWatchList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// watchEntry returns the watch list entry that proc matches, or "" if
+// none. An entry matches the process name or the base name of its
+// executable, ignoring case, so "postgres" doesn't also pick out every
+// process with postgres somewhere in its arguments.
+func (m model) watchEntry(proc ProcessInfo) string {
+	exe, _, _ := strings.Cut(proc.Cmdline, " ")
+	exe = filepath.Base(exe)
+	for _, name := range m.watch {
+		if strings.EqualFold(proc.Name, name) || strings.EqualFold(exe, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// checkWatched records which watched processes are running and reports
+// any that were running at the previous refresh but have since gone.
+func (m *model) checkWatched() bool {
+	if len(m.watch) == 0 {
+		return false
+	}
+
+	running := make(map[string]bool, len(m.watch))
+	for _, proc := range m.stats.processInfo {
+		if name := m.watchEntry(proc); name != "" {
+			running[name] = true
+		}
+	}
+
+	var gone []string
+	for _, name := range m.watch {
+		if m.watchSeen[name] && !running[name] {
+			gone = append(gone, name)
+		}
+	}
+	m.watchSeen = running
+
+	if len(gone) == 0 {
+		return false
+	}
+	m.notice = fmt.Sprintf("Watched process stopped: %s", strings.Join(gone, ", "))
+	return true
+}